@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetAutoInputDetection reports whether auto input detection is enabled.
+//
+// Like GetBuzzerState, it returns the state last set through this Switch, or
+// ErrStateUnknown, because the switch cannot report it.
+func (t *Switch) GetAutoInputDetection(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.settings.autoDetectKnown {
+		return false, fmt.Errorf("auto input detection: %w", ErrStateUnknown)
+	}
+	return t.settings.autoDetect, nil
+}
+
+// autoDetectOn reports whether auto input detection is known to be enabled.
+func (t *Switch) autoDetectOn() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.settings.autoDetectKnown && t.settings.autoDetect
+}
+
+// SwitchInputForce switches to input even if auto input detection is
+// enabled. With auto input detection on, the switch may move straight back
+// to an input with an active source, so SwitchInput merely logs a warning in
+// that case; SwitchInputForce instead disables auto input detection, switches
+// and, if reenable is true, enables it again, with no other command in
+// between. Re-enabling it lets the switch override the choice again as soon
+// as the sources change.
+//
+// On a model without auto input detection it is the same as
+// SwitchInputContext without debouncing. The error says which step failed.
+func (t *Switch) SwitchInputForce(ctx context.Context, input int, reenable bool) error {
+	cmds := []Command{{Kind: CmdSwitchInput, Arg: input}}
+	if t.model.SupportsAutoInputDetection() {
+		cmds = append([]Command{{Kind: CmdDisableAutoInputDetection}}, cmds...)
+		if reenable {
+			cmds = append(cmds, Command{Kind: CmdEnableAutoInputDetection})
+		}
+	}
+	if err := t.ExecBatch(ctx, cmds...); err != nil {
+		return fmt.Errorf("force %w", err)
+	}
+	return nil
+}
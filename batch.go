@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+)
+
+// CommandKind identifies a command in a batch.
+type CommandKind int
+
+const (
+	CmdSwitchInput CommandKind = iota + 1
+	CmdSetLedTimeout
+	CmdMuteBuzzer
+	CmdUnmuteBuzzer
+	CmdEnableAutoInputDetection
+	CmdDisableAutoInputDetection
+	CmdGetCurrentInput
+)
+
+// Command is a single step of a batch for ExecBatch. Arg is the input for
+// CmdSwitchInput and the timeout in seconds for CmdSetLedTimeout; the other
+// kinds ignore it.
+type Command struct {
+	Kind CommandKind
+	Arg  int
+}
+
+func (c Command) String() string {
+	switch c.Kind {
+	case CmdSwitchInput:
+		return fmt.Sprintf("switch input %d", c.Arg)
+	case CmdSetLedTimeout:
+		return fmt.Sprintf("set LED timeout %d", c.Arg)
+	case CmdMuteBuzzer:
+		return "mute buzzer"
+	case CmdUnmuteBuzzer:
+		return "unmute buzzer"
+	case CmdEnableAutoInputDetection:
+		return "enable auto input detection"
+	case CmdDisableAutoInputDetection:
+		return "disable auto input detection"
+	case CmdGetCurrentInput:
+		return "get current input"
+	}
+	return fmt.Sprintf("command %d", c.Kind)
+}
+
+// build returns the frame for c after checking it against the model.
+func (t *Switch) build(c Command) ([]byte, error) {
+	switch c.Kind {
+	case CmdSwitchInput:
+		if err := t.checkInput(c.Arg); err != nil {
+			return nil, err
+		}
+		return BuildSwitchInput(c.Arg)
+	case CmdSetLedTimeout:
+		if err := t.checkLedTimeout(c.Arg); err != nil {
+			return nil, err
+		}
+		return buildSetLedTimeout(c.Arg, t.MaxLedTimeout())
+	case CmdMuteBuzzer:
+		return BuildMuteBuzzer(), nil
+	case CmdUnmuteBuzzer:
+		return BuildUnmuteBuzzer(), nil
+	case CmdEnableAutoInputDetection:
+		if err := t.checkAutoInputDetection(); err != nil {
+			return nil, err
+		}
+		return BuildEnableAutoInputDetection(), nil
+	case CmdDisableAutoInputDetection:
+		if err := t.checkAutoInputDetection(); err != nil {
+			return nil, err
+		}
+		return BuildDisableAutoInputDetection(), nil
+	case CmdGetCurrentInput:
+		return BuildGetCurrentInput(), nil
+	}
+	return nil, fmt.Errorf("%v: %w", c, ErrInvalidInput)
+}
+
+// ExecBatch sends cmds in order, with no command from another goroutine in
+// between. Every command is checked before the first is sent, so an invalid
+// one fails the batch without sending anything. Otherwise the batch stops at
+// the first command that fails to send, and the error says which step it
+// was. Switches are sent at once, even with WithDebounce.
+func (t *Switch) ExecBatch(ctx context.Context, cmds ...Command) error {
+	frames := make([][]byte, len(cmds))
+	for i, c := range cmds {
+		frame, err := t.build(c)
+		if err != nil {
+			return fmt.Errorf("batch step %d: %w", i+1, err)
+		}
+		frames[i] = frame
+	}
+
+	if i, err := t.sendSequence(ctx, frames...); err != nil {
+		return fmt.Errorf("batch step %d: %v: %w", i+1, cmds[i], err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package commands
+
+import "fmt"
+
+// The Build functions return the frame for a command without sending it,
+// e.g. for logging or for transports the package does not know about. Each
+// call returns a new slice that the caller may modify.
+//
+// Every command frame is assembled by buildFrame, so the last byte follows
+// one rule instead of being copied from the command variables. Unlike
+// reports, whose last byte is a checksum of the input (see Protocol), the
+// commands documented for TESmart switches all end in the fixed terminator
+// 0xEE, whatever their value byte: AA BB 03 01 02 EE switches to input 2 and
+// AA BB 03 03 0A EE sets a 10 second LED timeout. The command variables
+// agree with that rule.
+
+// The opcodes of the commands. The builders use them rather than the
+// exported command variables, which callers can modify.
+const (
+	opSwitchInput        = 0x01
+	opBuzzer             = 0x02 // value 0 mutes, 1 unmutes
+	opSetLedTimeout      = 0x03
+	opGetCurrentInput    = 0x10
+	opAutoInputDetection = 0x81 // value 1 enables, 0 disables
+)
+
+// maxInputs is the largest number of inputs of any supported model.
+const maxInputs = 16
+
+// maxLedTimeout is the longest LED timeout, in seconds, the switch accepts.
+const maxLedTimeout = 30
+
+// BuildSwitchInput returns the frame that switches to input, numbered from
+// 1. It only checks that input is valid for some model; Switch.SwitchInput
+// also checks it against the configured model.
+func BuildSwitchInput(input int) ([]byte, error) {
+	if input < 1 || input > maxInputs {
+		return nil, fmt.Errorf("switch input %d: %w (must be 1-%d)", input, ErrInvalidInput, maxInputs)
+	}
+	return buildFrame(opSwitchInput, byte(input)), nil
+}
+
+// BuildSetLedTimeout returns the frame that sets the LED timeout to secs
+// seconds. 0 disables the timeout. secs is checked against the documented
+// range of 0-30 seconds, whatever WithMaxLedTimeout allows a Switch.
+func BuildSetLedTimeout(secs int) ([]byte, error) {
+	return buildSetLedTimeout(secs, maxLedTimeout)
+}
+
+// buildSetLedTimeout is BuildSetLedTimeout for a range of 0-max seconds, as
+// set with WithMaxLedTimeout.
+func buildSetLedTimeout(secs, max int) ([]byte, error) {
+	if secs < 0 || secs > max {
+		return nil, fmt.Errorf("set LED timeout %d: %w (must be 0-%d)", secs, ErrInvalidInput, max)
+	}
+	return buildFrame(opSetLedTimeout, byte(secs)), nil
+}
+
+// BuildMuteBuzzer returns the frame that mutes the buzzer.
+func BuildMuteBuzzer() []byte {
+	return buildFrame(opBuzzer, 0x00)
+}
+
+// BuildUnmuteBuzzer returns the frame that unmutes the buzzer.
+func BuildUnmuteBuzzer() []byte {
+	return buildFrame(opBuzzer, 0x01)
+}
+
+// BuildEnableAutoInputDetection returns the frame that enables automatic
+// input detection.
+func BuildEnableAutoInputDetection() []byte {
+	return buildFrame(opAutoInputDetection, 0x01)
+}
+
+// BuildDisableAutoInputDetection returns the frame that disables automatic
+// input detection.
+func BuildDisableAutoInputDetection() []byte {
+	return buildFrame(opAutoInputDetection, 0x00)
+}
+
+// BuildGetCurrentInput returns the frame that asks the switch for its active
+// input.
+func BuildGetCurrentInput() []byte {
+	return buildFrame(opGetCurrentInput, 0x00)
+}
+
+// commandTerminator ends every command frame in the default layout.
+const commandTerminator = 0xEE
+
+// buildFrame returns the command frame with opcode and value in the default
+// layout, that of the command variables; the Protocol of a switch is applied
+// when the frame is written.
+func buildFrame(opcode, value byte) []byte {
+	return []byte{0xAA, 0xBB, 0x03, opcode, value, commandTerminator}
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestBuilders(t *testing.T) {
+	must := func(frame []byte, err error) []byte {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return frame
+	}
+
+	// Frames as sent by the vendor's control software.
+	tests := []struct {
+		name  string
+		frame []byte
+		want  string
+	}{
+		{"switch to input 1", must(BuildSwitchInput(1)), "AA BB 03 01 01 EE"},
+		{"switch to input 2", must(BuildSwitchInput(2)), "AA BB 03 01 02 EE"},
+		{"switch to input 16", must(BuildSwitchInput(16)), "AA BB 03 01 10 EE"},
+		{"disable LED timeout", must(BuildSetLedTimeout(0)), "AA BB 03 03 00 EE"},
+		{"10s LED timeout", must(BuildSetLedTimeout(10)), "AA BB 03 03 0A EE"},
+		{"30s LED timeout", must(BuildSetLedTimeout(30)), "AA BB 03 03 1E EE"},
+		{"mute buzzer", BuildMuteBuzzer(), "AA BB 03 02 00 EE"},
+		{"unmute buzzer", BuildUnmuteBuzzer(), "AA BB 03 02 01 EE"},
+		{"enable auto input detection", BuildEnableAutoInputDetection(), "AA BB 03 81 01 EE"},
+		{"disable auto input detection", BuildDisableAutoInputDetection(), "AA BB 03 81 00 EE"},
+		{"get current input", BuildGetCurrentInput(), "AA BB 03 10 00 EE"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf("% X", tt.frame); got != tt.want {
+			t.Errorf("%s: built %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// The command variables agree with the builders.
+	for name, pair := range map[string][2][]byte{
+		"MUTE_BUZZER":                  {MUTE_BUZZER, BuildMuteBuzzer()},
+		"UNMUTE_BUZZER":                {UNMUTE_BUZZER, BuildUnmuteBuzzer()},
+		"ENABLE_AUTO_INPUT_DETECTION":  {ENABLE_AUTO_INPUT_DETECTION, BuildEnableAutoInputDetection()},
+		"DISABLE_AUTO_INPUT_DETECTION": {DISABLE_AUTO_INPUT_DETECTION, BuildDisableAutoInputDetection()},
+		"GET_CURRENT_INPUT":            {GET_CURRENT_INPUT, BuildGetCurrentInput()},
+	} {
+		if !bytes.Equal(pair[0], pair[1]) {
+			t.Errorf("%s = % X, but its builder returns % X", name, pair[0], pair[1])
+		}
+	}
+}
+
+func TestBuildersReturnNewSlices(t *testing.T) {
+	frame := BuildMuteBuzzer()
+	frame[4] = 0xFF
+	if !bytes.Equal(BuildMuteBuzzer(), MUTE_BUZZER) {
+		t.Error("modifying a built frame changed later ones")
+	}
+}
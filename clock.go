@@ -0,0 +1,45 @@
+package commands
+
+import "time"
+
+// clock is where the timing logic of a Switch gets the time from: backoff,
+// retries, debounce, rate limiting, query windows, the health check, and the
+// timestamps of captures and their replay. It lets that logic run against a
+// fake clock that is advanced by hand instead of waiting in real time, see
+// fakeClock in the tests. Socket deadlines are enforced by the operating
+// system and always use the real time.
+type clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) clockTimer
+	NewTicker(d time.Duration) clockTicker
+}
+
+// clockTimer is the subset of *time.Timer the package uses.
+type clockTimer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// clockTicker is the subset of *time.Ticker the package uses.
+type clockTicker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// realClock is the clock of the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) clockTimer { return realTimer{time.NewTimer(d)} }
+
+func (realClock) NewTicker(d time.Duration) clockTicker { return realTicker{time.NewTicker(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
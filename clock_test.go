@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock that only moves when advanced. Timers and tickers
+// fire, in order, as Advance passes their deadline.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	armed  chan struct{} // signalled whenever a timer or ticker is armed
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		armed: make(chan struct{}, 1),
+	}
+}
+
+// withClock makes the switch use c. Options that read the clock, such as
+// WithRateLimit, get it from newSwitch, so the order of options does not
+// matter.
+func withClock(c clock) Option {
+	return func(t *Switch) {
+		t.clock = c
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) clockTimer {
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1)}
+	c.add(t)
+	t.Reset(d)
+	return t
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) clockTicker {
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1), period: d}
+	c.add(t)
+	t.Reset(d)
+	return fakeTicker{t}
+}
+
+// fakeTicker adapts a periodic fakeTimer to clockTicker.
+type fakeTicker struct{ *fakeTimer }
+
+func (t fakeTicker) Stop() { t.fakeTimer.Stop() }
+
+func (c *fakeClock) add(t *fakeTimer) {
+	c.mu.Lock()
+	c.timers = append(c.timers, t)
+	c.mu.Unlock()
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker whose
+// deadline is passed on the way.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		due := c.dueLocked(end)
+		if due == nil {
+			break
+		}
+		c.now = due.when
+		due.fireLocked()
+	}
+	c.now = end
+}
+
+// dueLocked returns the armed timer with the earliest deadline not after end.
+func (c *fakeClock) dueLocked(end time.Time) *fakeTimer {
+	var armed []*fakeTimer
+	for _, t := range c.timers {
+		if t.active && !t.when.After(end) {
+			armed = append(armed, t)
+		}
+	}
+	if len(armed) == 0 {
+		return nil
+	}
+	sort.SliceStable(armed, func(i, j int) bool { return armed[i].when.Before(armed[j].when) })
+	return armed[0]
+}
+
+// next returns how far away the earliest deadline of an armed timer or
+// ticker is, waiting up to a second for one to be armed.
+func (c *fakeClock) next(tb testing.TB) time.Duration {
+	tb.Helper()
+
+	deadline := time.After(time.Second)
+	for {
+		c.mu.Lock()
+		var earliest *fakeTimer
+		for _, t := range c.timers {
+			if t.active && (earliest == nil || t.when.Before(earliest.when)) {
+				earliest = t
+			}
+		}
+		c.mu.Unlock()
+		if earliest != nil {
+			return earliest.when.Sub(c.Now())
+		}
+
+		select {
+		case <-c.armed:
+		case <-deadline:
+			tb.Fatal("no timer was armed")
+		}
+	}
+}
+
+// AdvanceToNext waits for a timer or ticker to be armed, advances the clock
+// to its deadline and returns how far it moved.
+func (c *fakeClock) AdvanceToNext(tb testing.TB) time.Duration {
+	tb.Helper()
+	d := c.next(tb)
+	c.Advance(d)
+	return d
+}
+
+// fakeTimer is a timer of a fakeClock, or a ticker if period is set.
+type fakeTimer struct {
+	clock  *fakeClock
+	ch     chan time.Time
+	period time.Duration
+	when   time.Time // guarded by clock.mu
+	active bool      // guarded by clock.mu
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	was := t.active
+	t.active = false
+	return was
+}
+
+// Reset arms the timer. Like a timer of the time package, one that is due
+// already fires at once.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	was := t.active
+	t.when = t.clock.now.Add(d)
+	t.active = true
+	if d <= 0 && t.period == 0 {
+		t.fireLocked()
+	}
+	t.clock.mu.Unlock()
+
+	select {
+	case t.clock.armed <- struct{}{}:
+	default:
+	}
+	return was
+}
+
+// fireLocked delivers the current time like the time package does: a send
+// is dropped if the last one was not received. clock.mu must be held.
+func (t *fakeTimer) fireLocked() {
+	select {
+	case t.ch <- t.clock.now:
+	default:
+	}
+	if t.period > 0 {
+		t.when = t.when.Add(t.period)
+	} else {
+		t.active = false
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	c := newFakeClock()
+	start := c.Now()
+
+	timer := c.NewTimer(2 * time.Second)
+	ticker := c.NewTicker(time.Second)
+
+	c.Advance(time.Second)
+	select {
+	case <-ticker.C():
+	default:
+		t.Fatal("ticker did not fire after 1s")
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired after 1s, want 2s")
+	default:
+	}
+
+	c.Advance(time.Second)
+	select {
+	case at := <-timer.C():
+		if got := at.Sub(start); got != 2*time.Second {
+			t.Errorf("timer fired at %v, want 2s", got)
+		}
+	default:
+		t.Fatal("timer did not fire after 2s")
+	}
+	if timer.Stop() {
+		t.Error("Stop of a fired timer = true, want false")
+	}
+
+	ticker.Stop()
+	if got := c.Now().Sub(start); got != 2*time.Second {
+		t.Errorf("Now moved by %v, want 2s", got)
+	}
+}
@@ -0,0 +1,52 @@
+// Command tesmart-grpc serves the gRPC service of package tesmartgrpc for a
+// single switch, e.g.
+//
+//	tesmart-grpc -host 192.168.1.10 -listen :9090
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+
+	commands "github.com/mfds/tesmart-commands"
+	"github.com/mfds/tesmart-commands/tesmartgrpc"
+	"github.com/mfds/tesmart-commands/tesmartgrpc/tesmartpb"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	host := flag.String("host", "192.168.1.10", "switch address")
+	port := flag.String("port", commands.DefaultPort, "switch TCP port")
+	listen := flag.String("listen", ":9090", "gRPC listen address")
+	ports := flag.Int("ports", 0, "number of switch inputs (8 or 16), if known")
+	flag.Parse()
+
+	srv := tesmartgrpc.New()
+
+	sw, err := commands.NewTesmartSwitch(*host, *port, nil,
+		commands.WithModel(commands.Model(*ports)),
+		commands.WithReconnect(0, 0),
+		commands.WithDebugFromEnv(),
+		commands.WithResponseReceiver(srv.HandleResponse))
+	if err != nil {
+		log.Fatalf("connect to %s: %v", net.JoinHostPort(*host, *port), err)
+	}
+	defer sw.Close()
+	srv.Attach(sw)
+
+	// Seed WatchInput with the current input; later changes arrive through
+	// the receiver.
+	sw.SendGetCurrentInput()
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalf("listen on %s: %v", *listen, err)
+	}
+
+	g := grpc.NewServer()
+	tesmartpb.RegisterSwitchServer(g, srv)
+
+	log.Printf("Serving switch %s on %s", net.JoinHostPort(*host, *port), *listen)
+	log.Fatal(g.Serve(ln))
+}
@@ -0,0 +1,60 @@
+// Command tesmart-mqtt bridges a switch to an MQTT broker, see package
+// mqttbridge for the topics, e.g.
+//
+//	tesmart-mqtt -host 192.168.1.10 -broker tcp://localhost:1883 -id office
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	commands "github.com/mfds/tesmart-commands"
+	"github.com/mfds/tesmart-commands/mqttbridge"
+)
+
+func main() {
+	host := flag.String("host", "192.168.1.10", "switch address")
+	port := flag.String("port", commands.DefaultPort, "switch TCP port")
+	ports := flag.Int("ports", 0, "number of switch inputs (8 or 16), if known")
+	broker := flag.String("broker", "tcp://localhost:1883", "MQTT broker URL")
+	id := flag.String("id", "switch", "switch ID used in topics")
+	username := flag.String("username", "", "MQTT username")
+	password := flag.String("password", os.Getenv("MQTT_PASSWORD"), "MQTT password, defaults to $MQTT_PASSWORD")
+	flag.Parse()
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(*broker).
+		SetClientID("tesmart-" + *id).
+		SetUsername(*username).
+		SetPassword(*password)
+
+	bridge := mqttbridge.New(opts, mqttbridge.Config{ID: *id})
+
+	sw, err := commands.NewTesmartSwitch(*host, *port, nil,
+		commands.WithModel(commands.Model(*ports)),
+		commands.WithReconnect(0, 0),
+		commands.WithDebugFromEnv(),
+		commands.WithResponseReceiver(bridge.HandleResponse),
+		commands.WithConnectionChangeHandler(bridge.HandleConnectionChange))
+	if err != nil {
+		log.Fatalf("connect to %s: %v", net.JoinHostPort(*host, *port), err)
+	}
+	defer sw.Close()
+
+	if err := bridge.Start(sw); err != nil {
+		log.Fatalf("connect to broker %s: %v", *broker, err)
+	}
+	defer bridge.Stop()
+
+	// Publish the initial state; later changes arrive through the receiver.
+	sw.SendGetCurrentInput()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+}
@@ -0,0 +1,44 @@
+// Command tesmart-server serves the HTTP API of package server for a single
+// switch, e.g.
+//
+//	tesmart-server -host 192.168.1.10 -listen :8080
+//	curl -X POST localhost:8080/input/3
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+
+	commands "github.com/mfds/tesmart-commands"
+	"github.com/mfds/tesmart-commands/server"
+)
+
+func main() {
+	host := flag.String("host", "192.168.1.10", "switch address")
+	port := flag.String("port", commands.DefaultPort, "switch TCP port")
+	listen := flag.String("listen", ":8080", "HTTP listen address")
+	ports := flag.Int("ports", 0, "number of switch inputs (8 or 16), if known")
+	flag.Parse()
+
+	hub := server.NewHub()
+
+	sw, err := commands.NewTesmartSwitch(*host, *port, nil,
+		commands.WithModel(commands.Model(*ports)),
+		commands.WithReconnect(0, 0),
+		commands.WithDebugFromEnv(),
+		commands.WithResponseReceiver(hub.HandleResponse),
+		commands.WithConnectionChangeHandler(hub.HandleConnectionChange))
+	if err != nil {
+		log.Fatalf("connect to %s: %v", net.JoinHostPort(*host, *port), err)
+	}
+	defer sw.Close()
+
+	// Seed the stream with the current input; later changes arrive through
+	// the receiver.
+	sw.SendGetCurrentInput()
+
+	log.Printf("Serving switch %s on %s", net.JoinHostPort(*host, *port), *listen)
+	log.Fatal(http.ListenAndServe(*listen, server.New(sw, server.WithStream(hub))))
+}
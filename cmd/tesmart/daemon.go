@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	commands "github.com/mfds/tesmart-commands"
+)
+
+// daemonRequest is sent by a client to the daemon, one per connection.
+type daemonRequest struct {
+	Addr    string        `json:"addr"` // switch the client means, host:port
+	Args    []string      `json:"args"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+type daemonResponse struct {
+	Result map[string]interface{} `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+
+	// WrongSwitch is set when the daemon is connected to another switch than
+	// the client asked for; the client then connects directly.
+	WrongSwitch bool `json:"wrong_switch,omitempty"`
+}
+
+// daemonReadTimeout bounds how long the daemon waits for a client to send its
+// request.
+const daemonReadTimeout = 5 * time.Second
+
+// defaultSocket returns the daemon socket path for the switch at host:port.
+// $XDG_RUNTIME_DIR is private to the user; the temporary directory is shared,
+// so the socket goes into a directory of the user's own there.
+func defaultSocket(host, port string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("tesmart-%d", os.Getuid()))
+	}
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(host + "-" + port)
+	return filepath.Join(dir, "tesmart-"+name+".sock")
+}
+
+// checkSocketDir makes sure that dir, which holds a daemon socket, is a
+// directory only the current user has access to. Otherwise another user
+// could send commands through the daemon, or put a socket of their own in
+// its place to receive the commands meant for it.
+func checkSocketDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("socket directory %s is not a directory", dir)
+	}
+	if perm := fi.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("socket directory %s is accessible by other users (mode %v)", dir, perm)
+	}
+	return checkOwner(dir, fi)
+}
+
+// runDaemon sends req to the daemon listening on socket. ok is false if no
+// daemon serves the switch at addr, in which case the caller should connect
+// directly.
+func runDaemon(ctx context.Context, socket, addr string, req request) (result map[string]interface{}, ok bool, err error) {
+	if checkSocketDir(filepath.Dir(socket)) != nil {
+		// Whatever listens there cannot be trusted to be our daemon.
+		return nil, false, nil
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, false, nil
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	dreq := daemonRequest{Addr: addr, Args: append([]string{req.Command}, req.Args...)}
+	if deadline, ok := ctx.Deadline(); ok {
+		dreq.Timeout = time.Until(deadline)
+	}
+	if err := json.NewEncoder(conn).Encode(dreq); err != nil {
+		return nil, true, fmt.Errorf("daemon %s: %w", socket, err)
+	}
+
+	var resp daemonResponse
+	dec := json.NewDecoder(conn)
+	dec.UseNumber()
+	if err := dec.Decode(&resp); err != nil {
+		return nil, true, fmt.Errorf("daemon %s: %w", socket, err)
+	}
+
+	switch {
+	case resp.WrongSwitch:
+		return nil, false, nil
+	case resp.Error != "":
+		return nil, true, fmt.Errorf("%s", resp.Error)
+	}
+	return resp.Result, true, nil
+}
+
+// daemon connects to the switch and serves commands on socket until it is
+// interrupted.
+func daemon(host, port, socket string) error {
+	addr := net.JoinHostPort(host, port)
+
+	dir := filepath.Dir(socket)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return err
+	}
+
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon is already listening on %s", socket)
+	}
+	if fi, err := os.Lstat(socket); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s exists and is not a socket", socket)
+		}
+		// Left behind by a daemon that did not exit cleanly.
+		if err := os.Remove(socket); err != nil {
+			return err
+		}
+	}
+
+	sw, err := commands.NewTesmartSwitch(host, port, nil,
+		commands.WithReconnect(0, 0),
+		commands.WithDebugFromEnv())
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	defer sw.Close()
+
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	if err := os.Chmod(socket, 0o600); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	log.Printf("Serving switch %s on %s", addr, socket)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go serveClient(ctx, sw, addr, conn)
+	}
+}
+
+func serveClient(ctx context.Context, sw *commands.Switch, addr string, conn net.Conn) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(daemonReadTimeout))
+	var req daemonRequest
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	var resp daemonResponse
+	if req.Addr != addr {
+		resp.WrongSwitch = true
+	} else if len(req.Args) == 0 {
+		resp.Error = "no command"
+	} else if r, err := parse(req.Args); err != nil {
+		resp.Error = fmt.Sprintf("invalid command %q", req.Args)
+	} else {
+		if req.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+			defer cancel()
+		}
+		resp.Result, err = execute(ctx, sw, r)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+	}
+
+	json.NewEncoder(conn).Encode(resp)
+}
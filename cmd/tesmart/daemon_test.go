@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultSocket(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if got, want := defaultSocket("::1", "5000"), "/run/user/1000/tesmart-__1-5000.sock"; got != want {
+		t.Errorf("defaultSocket with XDG_RUNTIME_DIR = %q, want %q", got, want)
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	want := filepath.Join(os.TempDir(), fmt.Sprintf("tesmart-%d", os.Getuid()))
+	if got := filepath.Dir(defaultSocket("192.0.2.1", "5000")); got != want {
+		t.Errorf("defaultSocket without XDG_RUNTIME_DIR is in %q, want %q", got, want)
+	}
+}
+
+func TestCheckSocketDir(t *testing.T) {
+	root := t.TempDir()
+	mkdir := func(name string, perm os.FileMode) string {
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, perm); err != nil {
+			t.Fatal(err)
+		}
+		// Not subject to the umask.
+		if err := os.Chmod(dir, perm); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	private := mkdir("private", 0o700)
+	shared := mkdir("shared", 0o777)
+	readable := mkdir("readable", 0o755)
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(private, link); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(root, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir   string
+		valid bool
+	}{
+		{private, true},
+		{shared, false},
+		{readable, false},
+		{link, false},
+		{file, false},
+		{filepath.Join(root, "missing"), false},
+	}
+	for _, tt := range tests {
+		if err := checkSocketDir(tt.dir); (err == nil) != tt.valid {
+			t.Errorf("checkSocketDir(%s) = %v, want valid %v", filepath.Base(tt.dir), err, tt.valid)
+		}
+	}
+}
+
+func TestDaemonKeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "tesmart.sock")
+	if err := os.WriteFile(socket, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := daemon("192.0.2.1", "5000", socket)
+	if err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("daemon on a regular file = %v, want a not a socket error", err)
+	}
+	if data, _ := os.ReadFile(socket); string(data) != "data" {
+		t.Error("daemon removed the file in the way of its socket")
+	}
+}
@@ -0,0 +1,217 @@
+// Command tesmart sends a single command to a switch and exits, e.g.
+//
+//	tesmart --host 192.168.1.10 switch 3
+//	tesmart --host 192.168.1.10 --json get-input
+//
+// Commands:
+//
+//	switch <n>          switch to input n
+//	get-input           print the active input
+//	mute, unmute        mute or unmute the buzzer
+//	led-timeout <secs>  set the LED timeout, 0 disables it
+//	auto-detect on|off  enable or disable auto input detection
+//	daemon              keep a connection open for other invocations
+//
+// Opening a connection for every command is slow when a script sends many of
+// them. "tesmart daemon" stays in the foreground, holds a connection to the
+// switch and serves commands on a Unix socket; later invocations for the same
+// switch go through it when it is running and connect directly otherwise:
+//
+//	tesmart --host 192.168.1.10 daemon &
+//	tesmart --host 192.168.1.10 switch 3
+//
+// The socket is named after the switch address and lives in $XDG_RUNTIME_DIR,
+// or if that is not set in a tesmart-<uid> directory in the temporary
+// directory; --socket overrides the path for both sides and --direct bypasses
+// the daemon. The directory of the socket must be accessible to its owner
+// only, and the daemon creates it if it does not exist, so that other users
+// can neither use nor impersonate the daemon.
+//
+// It exits with status 1 if the command fails and 2 on usage errors.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	commands "github.com/mfds/tesmart-commands"
+)
+
+var errUsage = errors.New("usage")
+
+func main() {
+	host := flag.String("host", "192.168.1.10", "switch address")
+	port := flag.String("port", commands.DefaultPort, "switch TCP port")
+	timeout := flag.Duration("timeout", 5*time.Second, "overall timeout")
+	jsonOutput := flag.Bool("json", false, "print results as JSON")
+	socket := flag.String("socket", "", "daemon socket (default derived from the switch address)")
+	direct := flag.Bool("direct", false, "connect to the switch even if a daemon is running")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	if *socket == "" {
+		*socket = defaultSocket(*host, *port)
+	}
+
+	if flag.Arg(0) == "daemon" {
+		if flag.NArg() != 1 {
+			usage()
+			os.Exit(2)
+		}
+		if err := daemon(*host, *port, *socket); err != nil {
+			fmt.Fprintf(os.Stderr, "tesmart: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *direct {
+		*socket = ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	result, err := run(ctx, *host, *port, *socket, flag.Args())
+	if errors.Is(err, errUsage) {
+		usage()
+		os.Exit(2)
+	}
+
+	if *jsonOutput {
+		if err != nil {
+			result = map[string]interface{}{"error": err.Error()}
+		}
+		json.NewEncoder(os.Stdout).Encode(result)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "tesmart: %v\n", err)
+	} else if input, ok := result["input"]; ok && flag.Arg(0) == "get-input" {
+		fmt.Println(input)
+	}
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: tesmart [flags] <command> [argument]
+
+Commands:
+  switch <n>          switch to input n
+  get-input           print the active input
+  mute, unmute        mute or unmute the buzzer
+  led-timeout <secs>  set the LED timeout, 0 disables it
+  auto-detect on|off  enable or disable auto input detection
+  daemon              keep a connection open for other invocations
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+// request is a command line checked by parse.
+type request struct {
+	Command string
+	Args    []string
+	arg     int
+	hasArg  bool
+}
+
+func parse(args []string) (request, error) {
+	req := request{Command: args[0], Args: args[1:]}
+
+	switch req.Command {
+	case "switch", "led-timeout":
+		if len(args) != 2 {
+			return req, errUsage
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return req, fmt.Errorf("%s: %q is not a number", req.Command, args[1])
+		}
+		req.arg, req.hasArg = n, true
+	case "auto-detect":
+		if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+			return req, errUsage
+		}
+	case "get-input", "mute", "unmute":
+		if len(args) != 1 {
+			return req, errUsage
+		}
+	default:
+		return req, errUsage
+	}
+
+	return req, nil
+}
+
+// run carries out the command line args, through the daemon listening on
+// socket if there is one for the switch, else over a connection of its own.
+func run(ctx context.Context, host, port, socket string, args []string) (map[string]interface{}, error) {
+	req, err := parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if socket != "" {
+		if result, ok, err := runDaemon(ctx, socket, net.JoinHostPort(host, port), req); ok {
+			return result, err
+		}
+	}
+
+	sw, err := commands.NewTesmartSwitchContext(ctx, host, port, nil,
+		commands.WithHealthCheck(false),
+		commands.WithDebugFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", net.JoinHostPort(host, port), err)
+	}
+	defer sw.Close()
+
+	return execute(ctx, sw, req)
+}
+
+func execute(ctx context.Context, sw *commands.Switch, req request) (map[string]interface{}, error) {
+	result := map[string]interface{}{"command": req.Command}
+	if req.hasArg {
+		result["value"] = req.arg
+	}
+
+	var err error
+	switch req.Command {
+	case "switch":
+		err = sw.SwitchInputContext(ctx, req.arg)
+	case "get-input":
+		var input int
+		input, err = sw.GetCurrentInput(ctx)
+		result["input"] = input
+	case "mute":
+		err = sw.MuteBuzzerContext(ctx)
+	case "unmute":
+		err = sw.UnmuteBuzzerContext(ctx)
+	case "led-timeout":
+		err = sw.SetLedTimeoutContext(ctx, req.arg)
+	case "auto-detect":
+		if req.Args[0] == "on" {
+			err = sw.EnableAutoInputDetectionContext(ctx)
+		} else {
+			err = sw.DisableAutoInputDetectionContext(ctx)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultPort is the TCP port TESmart switches listen on out of the box.
+const DefaultPort = "5000"
+
+// Config describes a switch connection declaratively, e.g. as loaded from a
+// configuration file. Zero values select the defaults.
+type Config struct {
+	Host string
+	Port string // DefaultPort if empty
+
+	DialTimeout  time.Duration // DefaultDialTimeout if zero
+	ReadDeadline time.Duration // DefaultReadDeadline if zero
+
+	Model     Model
+	Labels    map[int]string // see WithLabels
+	Reconnect bool           // reconnect with the default backoff, see WithReconnect
+
+	TLS      *tls.Config  // see WithTLS, nil for plain TCP
+	Logger   *log.Logger  // see WithLogger
+	Receiver func([]byte) // receives valid reports, may be nil
+
+	// Options are applied after the fields above, for settings Config has
+	// no field for.
+	Options []Option
+}
+
+// NewTesmartSwitchFromConfig checks cfg and connects to the switch it
+// describes, like NewTesmartSwitch.
+func NewTesmartSwitchFromConfig(cfg Config) (*Switch, error) {
+	return NewTesmartSwitchFromConfigContext(context.Background(), cfg)
+}
+
+// NewTesmartSwitchFromConfigContext is like NewTesmartSwitchFromConfig but
+// gives up dialing when ctx is done.
+func NewTesmartSwitchFromConfigContext(ctx context.Context, cfg Config) (*Switch, error) {
+	opts, err := cfg.options()
+	if err != nil {
+		return nil, err
+	}
+
+	port := cfg.Port
+	if port == "" {
+		port = DefaultPort
+	}
+
+	return NewTesmartSwitchContext(ctx, cfg.Host, port, cfg.Receiver, opts...)
+}
+
+func (cfg Config) options() ([]Option, error) {
+	switch cfg.Model {
+	case ModelUnknown, Model8, Model16, ModelMatrix4x4, ModelMatrix8x8:
+	default:
+		return nil, fmt.Errorf("invalid model: %d (must be 0, 8, 16 or a matrix model)", int(cfg.Model))
+	}
+	for input := range cfg.Labels {
+		if input < 1 || input > cfg.Model.Inputs() {
+			return nil, fmt.Errorf("invalid label for input %d: %w (must be 1-%d)", input, ErrInvalidInput, cfg.Model.Inputs())
+		}
+	}
+	if cfg.DialTimeout < 0 {
+		return nil, fmt.Errorf("invalid dial timeout: %v", cfg.DialTimeout)
+	}
+	if cfg.ReadDeadline != 0 && cfg.ReadDeadline < MinReadDeadline {
+		return nil, fmt.Errorf("invalid read deadline: %v (must be at least %v)", cfg.ReadDeadline, MinReadDeadline)
+	}
+
+	opts := []Option{
+		WithModel(cfg.Model),
+		WithDialTimeout(cfg.DialTimeout),
+		WithReadDeadline(cfg.ReadDeadline),
+	}
+	if cfg.Labels != nil {
+		opts = append(opts, WithLabels(cfg.Labels))
+	}
+	if cfg.Reconnect {
+		opts = append(opts, WithReconnect(0, 0))
+	}
+	if cfg.TLS != nil {
+		opts = append(opts, WithTLS(cfg.TLS))
+	}
+	if cfg.Logger != nil {
+		opts = append(opts, WithLogger(cfg.Logger))
+	}
+
+	return append(opts, cfg.Options...), nil
+}
@@ -0,0 +1,764 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Dialer opens connections to the switch. *net.Dialer implements it; tests can
+// supply their own to hand out in-memory connections.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// ConnectionState describes the state of the connection to the switch. The
+// numeric values are stable.
+type ConnectionState int
+
+const (
+	Disconnected ConnectionState = iota
+	Connected
+	Reconnecting
+)
+
+// String returns "disconnected", "connected" or "reconnecting".
+func (s ConnectionState) String() string {
+	switch s {
+	case Disconnected:
+		return "disconnected"
+	case Connected:
+		return "connected"
+	case Reconnecting:
+		return "reconnecting"
+	}
+	return fmt.Sprintf("ConnectionState(%d)", int(s))
+}
+
+// setStateLocked records a state transition and queues it for the connection
+// change handler. t.mu must be held.
+func (t *Switch) setStateLocked(state ConnectionState) {
+	if t.state == state {
+		return
+	}
+	t.state = state
+
+	if handler := t.connectionChangeHandler; handler != nil {
+		t.notifier.post(func() { handler(state) })
+	}
+	for _, watcher := range t.stateWatchers {
+		t.notifier.post(func() { watcher(state) })
+	}
+}
+
+// watchState registers an additional connection change handler after
+// construction, e.g. for a Manager.
+func (t *Switch) watchState(watcher func(ConnectionState)) {
+	t.mu.Lock()
+	t.stateWatchers = append(t.stateWatchers, watcher)
+	t.mu.Unlock()
+}
+
+// State returns the current connection state.
+func (t *Switch) State() ConnectionState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
+
+// RemoteAddr returns the address of the switch on the current connection, or
+// nil while disconnected or if the transport has no address, as with a
+// serial port.
+func (t *Switch) RemoteAddr() net.Addr {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if conn, ok := t.conn.(interface{ RemoteAddr() net.Addr }); ok {
+		return conn.RemoteAddr()
+	}
+	return nil
+}
+
+// Done returns a channel that is closed when the connection to the switch is
+// lost for good: when it drops and the switch is not reconnecting, see
+// WithReconnect, when reconnecting gives up, see WithReconnectAttempts, or
+// when Close is called. It stays open while the switch is reconnecting.
+func (t *Switch) Done() <-chan struct{} {
+	return t.done
+}
+
+// finish closes the channel returned by Done.
+func (t *Switch) finish() {
+	t.doneOnce.Do(func() { close(t.done) })
+}
+
+// Close sends a switch still pending because of WithDebounce, cancels the
+// connection, closes the socket and waits for the background loops to exit.
+// It is safe to call Close multiple times; every call returns the error, if
+// any, from closing the underlying connection.
+func (t *Switch) Close() error {
+	t.closeOnce.Do(func() {
+		t.stopDebounce()
+		t.cancel()
+
+		t.mu.Lock()
+		if t.conn != nil {
+			t.cancelFunc()
+			t.closeErr = t.conn.Close()
+			t.conn = nil
+		}
+		t.setStateLocked(Disconnected)
+		t.mu.Unlock()
+		t.finish()
+
+		t.wg.Wait()
+		t.notifier.close()
+		t.tapNotifier.close()
+		close(t.reports)
+	})
+	t.wg.Wait()
+	return t.closeErr
+}
+
+// readDeadliner and writeDeadliner are implemented by transports, such as
+// net.Conn, whose reads and writes can time out.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// connect opens the transport and attaches it. ctx only bounds opening it.
+func (t *Switch) connect(ctx context.Context) error {
+	if t.dryRun {
+		return t.attach(&t.recorder)
+	}
+
+	conn, err := t.open(ctx)
+	if err != nil {
+		return err
+	}
+
+	return t.attach(conn)
+}
+
+func (t *Switch) dialTCP(ctx context.Context) (io.ReadWriteCloser, error) {
+	t.logger.Print("Connecting...")
+
+	dialCtx, cancel := context.WithTimeout(ctx, t.dialTimeout)
+	defer cancel()
+
+	address := net.JoinHostPort(t.host, t.port)
+	conn, err := t.dialer.DialContext(dialCtx, "tcp", address)
+	if err != nil {
+		t.logger.Printf("Failed to dial: %v", err)
+		return nil, err
+	}
+
+	if tcp, ok := conn.(*net.TCPConn); ok && t.keepAlive != 0 {
+		if err := setKeepAlive(tcp, t.keepAlive); err != nil {
+			t.logger.Printf("Failed to configure keepalive: %v", err)
+		}
+	}
+
+	if t.tlsConfig != nil {
+		cfg := t.tlsConfig
+		if cfg.ServerName == "" {
+			cfg = cfg.Clone()
+			cfg.ServerName = t.host
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(dialCtx); err != nil {
+			t.logger.Printf("TLS handshake failed: %v", err)
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with %s: %w", address, err)
+		}
+		conn = tlsConn
+	}
+
+	t.logger.Printf("Connected to: %s", address)
+
+	return conn, nil
+}
+
+func setKeepAlive(conn *net.TCPConn, period time.Duration) error {
+	if period < 0 {
+		return conn.SetKeepAlive(false)
+	}
+	if err := conn.SetKeepAlive(true); err != nil {
+		return err
+	}
+	return conn.SetKeepAlivePeriod(period)
+}
+
+// attach makes conn the current connection and starts its background loops.
+func (t *Switch) attach(conn io.ReadWriteCloser) error {
+	ctx, cancel := context.WithCancel(t.ctx)
+
+	t.mu.Lock()
+	if t.ctx.Err() != nil {
+		t.mu.Unlock()
+		cancel()
+		conn.Close()
+		return ErrNotConnected
+	}
+	if t.conn != nil {
+		// Reconnect and the reconnect loop raced; keep the newer one.
+		t.cancelFunc()
+		t.conn.Close()
+	}
+	t.conn = conn
+	t.connectionCtx = ctx
+	t.cancelFunc = cancel
+	t.setStateLocked(Connected)
+
+	// Matrix switches do not answer the binary query the health check
+	// probes with.
+	receive := !t.dryRun
+	check := receive && !t.noHealthCheck && !t.model.IsMatrix()
+
+	// The loops are added to the WaitGroup before t.mu is released, so
+	// that a Close taking it next waits for them even if they have not
+	// started yet.
+	if receive {
+		t.wg.Add(1)
+	}
+	if check {
+		t.wg.Add(1)
+	}
+	t.mu.Unlock()
+
+	atomic.StoreInt64(&t.lastRead, t.clock.Now().UnixNano())
+	// The input may have changed while disconnected.
+	t.inputFresh.Store(false)
+
+	if receive {
+		go t.receiveLoop(ctx, conn)
+	}
+	if check {
+		go t.checkConnectionLoop(ctx, conn)
+	}
+
+	t.logEvent(slog.LevelInfo, "connected")
+
+	return nil
+}
+
+// connectionLost tears down conn after a read failure or an unanswered health
+// check and, if enabled, starts reconnecting. It does nothing when conn is no
+// longer the current connection, so every loss is handled exactly once.
+func (t *Switch) connectionLost(conn io.ReadWriteCloser, reason error) {
+	t.mu.Lock()
+	if t.conn != conn {
+		t.mu.Unlock()
+		return
+	}
+	t.cancelFunc()
+	conn.Close()
+	t.conn = nil
+	t.setStateLocked(Disconnected)
+
+	reconnect := t.reconnect && t.open != nil && t.ctx.Err() == nil
+	if reconnect {
+		t.setStateLocked(Reconnecting)
+		t.wg.Add(1)
+	}
+	t.mu.Unlock()
+
+	t.logger.Printf("Disconnected: %v", reason)
+	t.logEvent(slog.LevelWarn, "disconnected", slog.Any("error", reason))
+	if t.disconnectHandler != nil {
+		t.disconnectHandler(reason)
+	}
+
+	if reconnect {
+		go t.reconnectLoop()
+	} else {
+		t.finish()
+	}
+}
+
+// Reconnect closes the current connection, if any, and dials the switch
+// again, e.g. after a known network change. It is safe to call at any time,
+// also while connected or while automatically reconnecting. If dialing
+// fails, the switch is left Disconnected and the error is returned; commands
+// fail with ErrNotConnected until Reconnect, or automatic reconnection if
+// enabled by WithReconnect, succeeds. Switches created from an existing
+// connection or transport cannot be reconnected. The disconnect handler is
+// not called, and Done is not reset once closed. Close aborts a dial in
+// progress.
+func (t *Switch) Reconnect(ctx context.Context) error {
+	if t.dryRun {
+		return nil
+	}
+	if t.open == nil {
+		return errors.New("reconnect: switch was created from an existing connection")
+	}
+
+	t.mu.Lock()
+	if t.ctx.Err() != nil {
+		t.mu.Unlock()
+		return fmt.Errorf("reconnect: %w", ErrNotConnected)
+	}
+	if t.conn != nil {
+		t.cancelFunc()
+		t.conn.Close()
+		t.conn = nil
+	}
+	t.setStateLocked(Disconnected)
+	t.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(t.ctx, cancel)
+	defer stop()
+
+	t.logger.Print("Reconnecting on request")
+	if err := t.connect(ctx); err != nil {
+		return fmt.Errorf("reconnect: %w", err)
+	}
+	return nil
+}
+
+// reconnectLoop re-dials the switch with exponential backoff until it
+// succeeds, the attempts set with WithReconnectAttempts are used up or the
+// switch is closed.
+func (t *Switch) reconnectLoop() {
+	defer t.wg.Done()
+
+	delay := t.reconnectBackoff
+	timer := t.clock.NewTimer(t.jitter(delay))
+	defer timer.Stop()
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-timer.C():
+		}
+
+		if t.State() == Connected {
+			// Reconnected by Reconnect in the meantime.
+			return
+		}
+
+		t.logger.Printf("Reconnect attempt %d", attempt)
+		err := t.connect(t.ctx)
+		if t.reconnectHandler != nil {
+			t.reconnectHandler(attempt, err)
+		}
+		if err == nil {
+			t.metrics.Reconnected()
+			return
+		}
+
+		if t.reconnectAttempts > 0 && attempt >= t.reconnectAttempts {
+			t.giveUpReconnecting(attempt, err)
+			return
+		}
+
+		delay *= 2
+		if delay > t.reconnectMax {
+			delay = t.reconnectMax
+		}
+		timer.Reset(t.jitter(delay))
+	}
+}
+
+// jitter shortens delay by a random part of up to t.reconnectJitter of it.
+func (t *Switch) jitter(delay time.Duration) time.Duration {
+	if t.reconnectJitter <= 0 {
+		return delay
+	}
+	return delay - time.Duration(rand.Float64()*t.reconnectJitter*float64(delay))
+}
+
+// giveUpReconnecting leaves the switch Disconnected for good after the last
+// allowed attempt failed with err.
+func (t *Switch) giveUpReconnecting(attempts int, err error) {
+	t.mu.Lock()
+	if t.conn != nil || t.ctx.Err() != nil {
+		// Reconnected by Reconnect or closed in the meantime.
+		t.mu.Unlock()
+		return
+	}
+	t.setStateLocked(Disconnected)
+	t.mu.Unlock()
+
+	t.logger.Printf("Giving up reconnecting after %d attempts: %v", attempts, err)
+	t.logEvent(slog.LevelError, "reconnect abandoned", slog.Int("attempts", attempts), slog.Any("error", err))
+	t.finish()
+}
+
+// send writes command to the current connection, retrying timed out writes
+// as configured with WithRetry. The write fails with ctx.Err() when ctx is
+// done before it completes.
+func (t *Switch) send(ctx context.Context, command []byte) error {
+	_, err := t.sendSequence(ctx, command)
+	return err
+}
+
+// lockWrite takes the write lock, which every write is made under so that
+// frames never interleave. Unlike a mutex it gives up, returning ctx.Err(),
+// when ctx is done first, so that a command stuck behind a blocked write still
+// honours its context.
+func (t *Switch) lockWrite(ctx context.Context) error {
+	select {
+	case t.writeLock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// unlockWrite releases the write lock taken by lockWrite.
+func (t *Switch) unlockWrite() {
+	<-t.writeLock
+}
+
+// acquire waits for the rate limiter to allow n commands and then takes the
+// write lock. The limiter is waited for first so that commands queued behind
+// the one over the limit still give up when their context is done. A failure
+// is accounted for as one of the command described by info.
+func (t *Switch) acquire(ctx context.Context, info CommandInfo, n int) (err error) {
+	if t.limiter != nil {
+		err = t.limiter.wait(ctx, n)
+	}
+	if err == nil {
+		if err = t.lockWrite(ctx); err != nil && t.limiter != nil {
+			t.limiter.release(n)
+		}
+	}
+	if err != nil {
+		_, end := t.tracer.StartCommand(ctx, info)
+		end(err)
+		t.metrics.SendFailed(info.Command)
+		t.stats.sendErrors.Add(1)
+	}
+	return err
+}
+
+// sendSequence sends commands in order without letting other commands in
+// between. It stops at the first failure and returns its index.
+func (t *Switch) sendSequence(ctx context.Context, commands ...[]byte) (int, error) {
+	if len(commands) == 0 {
+		return 0, nil
+	}
+	if err := t.acquire(ctx, t.commandInfo(commands[0]), len(commands)); err != nil {
+		return 0, err
+	}
+	for i, command := range commands {
+		lost, err := t.sendLocked(ctx, t.commandInfo(command), t.protocol.encode(command))
+		if err != nil {
+			t.unlockWrite()
+
+			// Handled without the write lock held: the disconnect handler
+			// may well issue a command itself.
+			if lost != nil {
+				t.connectionLost(lost, err)
+			}
+			return i, err
+		}
+		t.remember(command)
+	}
+	t.unlockWrite()
+
+	return len(commands), nil
+}
+
+// sendFrame writes frame, which is in the wire format of t.protocol or one
+// of its own, as the command described by info.
+func (t *Switch) sendFrame(ctx context.Context, info CommandInfo, frame []byte) error {
+	if err := t.acquire(ctx, info, 1); err != nil {
+		return err
+	}
+	lost, err := t.sendLocked(ctx, info, frame)
+	t.unlockWrite()
+
+	if lost != nil {
+		t.connectionLost(lost, err)
+	}
+	return err
+}
+
+// sendLocked writes frame, which is in the wire format of t.protocol, and
+// accounts for the outcome of the command described by info. When the write
+// failed because the connection is unusable, that connection is returned as
+// lost. The write lock must be held, see acquire.
+func (t *Switch) sendLocked(ctx context.Context, info CommandInfo, frame []byte) (lost io.ReadWriteCloser, err error) {
+	name := info.Command
+	ctx, end := t.tracer.StartCommand(ctx, info)
+	defer func() { end(err) }()
+
+	lost, err = t.sendRetry(ctx, name, frame)
+	if err != nil {
+		t.metrics.SendFailed(name)
+		t.stats.sendErrors.Add(1)
+		return lost, err
+	}
+
+	t.metrics.CommandSent(name)
+	t.stats.commandsSent.Add(1)
+	t.stats.lastSent.Store(t.clock.Now().UnixNano())
+	return nil, nil
+}
+
+// sendRetry writes frame, retrying timed out writes. The write lock must be
+// held.
+func (t *Switch) sendRetry(ctx context.Context, name string, frame []byte) (lost io.ReadWriteCloser, err error) {
+	for attempt := 0; ; attempt++ {
+		last := attempt >= t.retries
+		lost, err = t.sendOnce(ctx, name, frame, last)
+		if err == nil || lost != nil || last || !isTimeout(err) {
+			return lost, err
+		}
+
+		t.logger.Printf("Retrying %s after: %v", name, err)
+		timer := t.clock.NewTimer(t.retryDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
+
+// sendOnce makes a single attempt at writing frame. A failed write means the
+// connection is lost, except for a timeout that will be retried, i.e. when
+// last is false. The write lock must be held.
+func (t *Switch) sendOnce(ctx context.Context, name string, frame []byte, last bool) (lost io.ReadWriteCloser, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+
+	err = t.write(ctx, conn, name, frame)
+	if err == nil {
+		return nil, nil
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// The TLS session is unusable after an interrupted write. Closing
+		// the connection beneath it keeps closing the session from
+		// blocking on a close_notify alert a stuck peer will not read.
+		tlsConn.NetConn().Close()
+		return conn, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if isTimeout(err) && !last && !errors.Is(err, ErrShortWrite) {
+		return nil, err
+	}
+
+	// Either the switch stopped reading, the connection is broken or a torn
+	// frame garbled the stream; treat it like any other lost connection so
+	// that reconnection, if enabled, kicks in.
+	return conn, err
+}
+
+// isTimeout reports whether err is a timed out read or write.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// write writes frame, the wire form of the command called name, to conn.
+// The write lock must be held.
+func (t *Switch) write(ctx context.Context, conn io.Writer, name string, frame []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.logger.Printf("Sending: %s", printHex(frame))
+
+	if deadliner, ok := conn.(writeDeadliner); ok {
+		deadline := time.Now().Add(t.writeTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		deadliner.SetWriteDeadline(deadline)
+		defer deadliner.SetWriteDeadline(time.Time{})
+
+		// Cancelling ctx moves the deadline into the past, which makes a
+		// blocked Write return immediately.
+		stop := context.AfterFunc(ctx, func() {
+			deadliner.SetWriteDeadline(time.Unix(1, 0))
+		})
+		defer stop()
+	}
+
+	// A stream may accept part of the frame; keep writing until all of it
+	// is sent.
+	bytesSent := 0
+	defer func() {
+		if name != probeCommand {
+			t.stats.bytesWritten.Add(uint64(bytesSent))
+		}
+		t.tap(Sent, frame[:bytesSent])
+	}()
+	for bytesSent < len(frame) {
+		n, err := conn.Write(frame[bytesSent:])
+		bytesSent += n
+
+		if err != nil {
+			t.logger.Printf("Failed to send command: %v", err)
+			t.logEvent(slog.LevelError, "send failed", slog.String("command", name), slog.Any("error", err))
+			if bytesSent > 0 {
+				// Part of the frame is on the wire; resending it
+				// would garble the stream.
+				return fmt.Errorf("%w: sent %d of %d bytes: %w", ErrShortWrite, bytesSent, len(frame), err)
+			}
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return fmt.Errorf("write timed out after %v: %w", t.writeTimeout, err)
+			}
+			return err
+		}
+
+		if n == 0 {
+			err := fmt.Errorf("%w: sent %d of %d bytes", ErrShortWrite, bytesSent, len(frame))
+			t.logger.Print(err)
+			return err
+		}
+		if bytesSent < len(frame) {
+			t.logger.Printf("Short write: sent %d of %d bytes, writing the rest", bytesSent, len(frame))
+		}
+	}
+
+	t.logger.Printf("Sent: %d", bytesSent)
+	t.logEvent(slog.LevelDebug, "command sent", slog.String("command", name), slog.Int("bytes", bytesSent))
+
+	return nil
+}
+
+// checkConnectionLoop periodically asks the switch for its current input and
+// declares the connection lost when nothing has been read since the previous
+// probe. The answers are delivered to the receiver like any other report.
+func (t *Switch) checkConnectionLoop(ctx context.Context, conn io.ReadWriteCloser) {
+	defer t.wg.Done()
+
+	ticker := t.clock.NewTicker(t.healthCheckInterval)
+	defer ticker.Stop()
+
+	var lastProbe time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		if !lastProbe.IsZero() && atomic.LoadInt64(&t.lastRead) < lastProbe.UnixNano() {
+			t.connectionLost(conn, fmt.Errorf("no response to health check within %v", t.healthCheckInterval))
+			return
+		}
+
+		lastProbe = t.clock.Now()
+		if err := t.sendProbe(ctx); err != nil {
+			t.connectionLost(conn, fmt.Errorf("health check: %w", err))
+			return
+		}
+		t.logger.Println("PING")
+	}
+}
+
+// probeCommand is the name health check probes are written under.
+const probeCommand = "health_check"
+
+// sendProbe writes the health check query. Unlike send it bypasses the rate
+// limiter: a probe turned away would count as a lost connection, and one
+// probe per interval cannot overwhelm the switch. Probes are not the user's
+// commands, so they are not traced or counted in Stats and Metrics either,
+// which would make an idle switch look busy.
+func (t *Switch) sendProbe(ctx context.Context) error {
+	if err := t.lockWrite(ctx); err != nil {
+		return err
+	}
+	lost, err := t.sendRetry(ctx, probeCommand, t.protocol.encode(BuildGetCurrentInput()))
+	t.unlockWrite()
+
+	if lost != nil {
+		t.connectionLost(lost, err)
+	}
+	return err
+}
+
+// receiveLoop reads from conn until it fails or ctx is cancelled, splitting
+// what it reads into frames.
+func (t *Switch) receiveLoop(ctx context.Context, conn io.ReadWriteCloser) {
+	defer t.wg.Done()
+
+	deadliner, _ := conn.(readDeadliner)
+
+	frames := newFramer(t.protocol)
+	buf := make([]byte, readBufferSize)
+
+ReadLoop:
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			if deadliner != nil {
+				deadliner.SetReadDeadline(time.Now().Add(t.readDeadline))
+			}
+			read, err := conn.Read(buf)
+			at := t.clock.Now()
+
+			if err != nil {
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					continue ReadLoop
+				} else if err != io.EOF {
+					t.logger.Printf("Failed to read data from socket: %v", err)
+					t.connectionLost(conn, err)
+					return
+				}
+			}
+
+			if read == 0 {
+				t.connectionLost(conn, io.EOF)
+				return
+			}
+
+			atomic.StoreInt64(&t.lastRead, at.UnixNano())
+			t.logger.Printf("Read %d bytes: %s", read, printHex(buf[:read]))
+			t.logEvent(slog.LevelDebug, "response received", slog.Int("bytes", read), slog.String("data", printHex(buf[:read])))
+
+			t.tap(Received, buf[:read])
+			if t.rawFunc != nil {
+				t.rawFunc(buf[:read])
+			}
+			if t.capture != nil {
+				if err := t.capture.write(at, buf[:read]); err != nil {
+					t.logger.Printf("Failed to capture read: %v", err)
+				}
+			}
+
+			complete, dropped := frames.push(buf[:read])
+			if dropped > 0 {
+				t.logger.Printf("Dropped %d bytes outside of a frame", dropped)
+			}
+			for _, frame := range complete {
+				t.dispatch(frame, at)
+			}
+		}
+	}
+}
@@ -0,0 +1,674 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dialerFunc adapts a function to the Dialer interface.
+type dialerFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+func (f dialerFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return f(ctx, network, address)
+}
+
+// pipeDialer dials net.Pipes whose far ends are passed to serve, which plays
+// the device, on a goroutine of its own. Far ends are closed once serve
+// returns. Use wait to wait for every serve call to return.
+type pipeDialer struct {
+	serve func(peer net.Conn)
+	wg    sync.WaitGroup
+}
+
+func (d *pipeDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, peer := net.Pipe()
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		defer peer.Close()
+		d.serve(peer)
+	}()
+	return client, nil
+}
+
+func (d *pipeDialer) wait() { d.wg.Wait() }
+
+// discard reads from peer until it is closed.
+func discard(peer net.Conn) {
+	buf := make([]byte, 64)
+	for {
+		if _, err := peer.Read(buf); err != nil {
+			return
+		}
+	}
+}
+
+// checkGoroutines fails the test if more goroutines than before are still
+// running shortly after cleanup, i.e. it leaked some.
+func checkGoroutines(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := runtime.NumGoroutine(); n > before {
+			buf := make([]byte, 1<<16)
+			t.Errorf("%d goroutines leaked:\n%s", n-before, buf[:runtime.Stack(buf, true)])
+		}
+	})
+}
+
+// report returns the frame the switch sends to report input as active.
+func report(input int) []byte {
+	return []byte{0xAA, 0xBB, 0x03, 0x11, byte(input - 1), byte(input - 1 + 0x16)}
+}
+
+func TestDialer(t *testing.T) {
+	peers := make(chan net.Conn, 1)
+	var dialed string
+	dialer := dialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		dialed = network + " " + address
+		client, peer := net.Pipe()
+		peers <- peer
+		return client, nil
+	})
+
+	sw, err := NewTesmartSwitch("192.0.2.1", "5000", nil, WithDialer(dialer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+	peer := <-peers
+	defer peer.Close()
+
+	if dialed != "tcp 192.0.2.1:5000" {
+		t.Errorf("dialed %q, want tcp 192.0.2.1:5000", dialed)
+	}
+
+	writes := readWrites(peer)
+	if err := sw.SwitchInput(2); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := next(t, writes), []byte{0xAA, 0xBB, 0x03, 0x01, 0x02, 0xEE}; !bytes.Equal(got, want) {
+		t.Errorf("SwitchInput(2) sent % X, want % X", got, want)
+	}
+}
+
+func TestReceiveFromConn(t *testing.T) {
+	frames := make(chan []byte, 1)
+	_, peer := pipeSwitch(t, func(frame []byte) { frames <- frame })
+
+	if _, err := peer.Write(report(3)); err != nil {
+		t.Fatal(err)
+	}
+	if got := next(t, frames); !bytes.Equal(got, report(3)) {
+		t.Errorf("received % X, want % X", got, report(3))
+	}
+}
+
+func TestReconnectRacingClose(t *testing.T) {
+	dialer := &pipeDialer{serve: discard}
+	checkGoroutines(t)
+	t.Cleanup(dialer.wait)
+
+	for range 50 {
+		sw, err := NewTesmartSwitch("192.0.2.1", "5000", nil, WithDialer(dialer))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reconnected := make(chan struct{})
+		go func() {
+			defer close(reconnected)
+			sw.Reconnect(context.Background())
+		}()
+		sw.Close()
+		<-reconnected
+
+		if state := sw.State(); state != Disconnected {
+			t.Fatalf("State() after Close = %v, want disconnected", state)
+		}
+	}
+}
+
+func TestCloseAbortsReconnect(t *testing.T) {
+	dialing := make(chan struct{}, 1)
+	dials := 0
+	dialer := dialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		dials++
+		if dials == 1 {
+			client, peer := net.Pipe()
+			go discard(peer)
+			return client, nil
+		}
+		dialing <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	sw, err := NewTesmartSwitch("192.0.2.1", "5000", nil, WithDialer(dialer), WithDialTimeout(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- sw.Reconnect(context.Background()) }()
+	next(t, dialing)
+
+	sw.Close()
+	if err := next(t, done); !errors.Is(err, context.Canceled) {
+		t.Errorf("Reconnect interrupted by Close = %v, want context.Canceled", err)
+	}
+}
+
+// flakyDialer connects once, handing the device end of the connection to
+// peers, and fails every later dial.
+func flakyDialer(peers chan<- net.Conn) Dialer {
+	dials := 0
+	return dialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		dials++
+		if dials > 1 {
+			return nil, errors.New("connection refused")
+		}
+		client, peer := net.Pipe()
+		peers <- peer
+		return client, nil
+	})
+}
+
+// lostSwitch returns a switch that has just lost its connection and fails
+// to reconnect, together with a channel receiving the reconnect attempts.
+func lostSwitch(t *testing.T, clock *fakeClock, opts ...Option) (*Switch, <-chan int) {
+	t.Helper()
+
+	peers := make(chan net.Conn, 1)
+	attempts := make(chan int, 16)
+	opts = append([]Option{
+		withClock(clock),
+		WithDialer(flakyDialer(peers)),
+		WithHealthCheck(false),
+		WithReconnectHandler(func(attempt int, err error) { attempts <- attempt }),
+	}, opts...)
+	sw, err := NewTesmartSwitch("192.0.2.1", "5000", nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sw.Close() })
+
+	next(t, peers).Close()
+	return sw, attempts
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	clock := newFakeClock()
+	_, attempts := lostSwitch(t, clock, WithReconnect(time.Second, 4*time.Second))
+
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if d := clock.AdvanceToNext(t); d != want {
+			t.Errorf("delay before attempt %d = %v, want %v", i+1, d, want)
+		}
+		if attempt := next(t, attempts); attempt != i+1 {
+			t.Fatalf("attempt %d reported as %d", i+1, attempt)
+		}
+	}
+}
+
+func TestReconnectJitter(t *testing.T) {
+	clock := newFakeClock()
+	_, attempts := lostSwitch(t, clock, WithReconnect(time.Second, 8*time.Second), WithReconnectJitter(0.5))
+
+	for i, base := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+		if d := clock.AdvanceToNext(t); d < base/2 || d > base {
+			t.Errorf("delay before attempt %d = %v, want %v-%v", i+1, d, base/2, base)
+		}
+		next(t, attempts)
+	}
+}
+
+func TestReconnectAttempts(t *testing.T) {
+	clock := newFakeClock()
+	sw, attempts := lostSwitch(t, clock, WithReconnect(time.Second, time.Second), WithReconnectAttempts(3))
+
+	for i := range 3 {
+		clock.AdvanceToNext(t)
+		if attempt := next(t, attempts); attempt != i+1 {
+			t.Fatalf("attempt %d reported as %d", i+1, attempt)
+		}
+	}
+
+	next(t, sw.Done())
+	if state := sw.State(); state != Disconnected {
+		t.Errorf("State() after the last attempt = %v, want disconnected", state)
+	}
+
+	// No further attempt is scheduled.
+	clock.Advance(time.Hour)
+	select {
+	case attempt := <-attempts:
+		t.Errorf("attempt %d made after giving up", attempt)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReceiveSplitFrame(t *testing.T) {
+	frames := make(chan []byte, 4)
+	_, peer := pipeSwitch(t, func(frame []byte) { frames <- frame })
+
+	first := report(3)
+	peer.Write(first[:2])
+	peer.Write(first[2:])
+	got := next(t, frames)
+	if !bytes.Equal(got, first) {
+		t.Fatalf("received % X, want % X", got, first)
+	}
+
+	// The next frame must not overwrite the one the receiver kept.
+	peer.Write(report(5))
+	next(t, frames)
+	if !bytes.Equal(got, first) {
+		t.Errorf("first frame changed to % X after the next read", got)
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	// Nothing reads from the peer, so every write blocks.
+	sw, _ := pipeSwitch(t, nil, WithWriteTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	err := sw.SwitchInput(2)
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("SwitchInput to a blocked peer = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("SwitchInput returned after %v, want about 20ms", elapsed)
+	}
+	if state := sw.State(); state != Disconnected {
+		t.Errorf("State() after the timed out write = %v, want disconnected", state)
+	}
+}
+
+func TestWriteLockHonoursContext(t *testing.T) {
+	// Nothing reads from the peer, so the first write blocks for good.
+	sw, peer := pipeSwitch(t, nil, WithWriteTimeout(time.Hour))
+
+	blocked := make(chan error, 1)
+	go func() { blocked <- sw.SwitchInput(1) }()
+	deadline := time.Now().Add(time.Second)
+	for len(sw.writeLock) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := sw.SwitchInputContext(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SwitchInputContext behind a blocked write = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("SwitchInputContext returned after %v, want about 50ms", elapsed)
+	}
+
+	peer.Close()
+	if err := next(t, blocked); err == nil {
+		t.Error("write to a closed peer succeeded")
+	}
+}
+
+func TestReceiveGarbage(t *testing.T) {
+	frames := make(chan []byte, 4)
+	sw, peer := pipeSwitch(t, func(frame []byte) { frames <- frame })
+
+	peer.Write(append([]byte{0x00, 0xFF, 0xAA}, report(6)...))
+
+	if got := next(t, frames); !bytes.Equal(got, report(6)) {
+		t.Errorf("received % X, want the frame after the garbage % X", got, report(6))
+	}
+	deadline := time.Now().Add(time.Second)
+	for input, _ := sw.LastKnownInput(); input != 6 && time.Now().Before(deadline); input, _ = sw.LastKnownInput() {
+		time.Sleep(time.Millisecond)
+	}
+	if input, _ := sw.LastKnownInput(); input != 6 {
+		t.Errorf("LastKnownInput() = %d, want 6", input)
+	}
+}
+
+func TestReceiveBadChecksum(t *testing.T) {
+	frames := make(chan []byte, 4)
+	sw, peer := pipeSwitch(t, func(frame []byte) { frames <- frame })
+
+	bad := report(2)
+	bad[5]++
+	peer.Write(append(bad, report(6)...))
+
+	if got := next(t, frames); !bytes.Equal(got, report(6)) {
+		t.Errorf("received % X, want the frame after the bad one % X", got, report(6))
+	}
+	deadline := time.Now().Add(time.Second)
+	for input, _ := sw.LastKnownInput(); input != 6 && time.Now().Before(deadline); input, _ = sw.LastKnownInput() {
+		time.Sleep(time.Millisecond)
+	}
+	if input, _ := sw.LastKnownInput(); input != 6 {
+		t.Errorf("LastKnownInput() = %d, want 6", input)
+	}
+}
+
+func TestReceiveWithoutReceiver(t *testing.T) {
+	sw, peer := pipeSwitch(t, nil)
+
+	// The second write only completes once the receive loop is done with
+	// the first frame and reads again.
+	peer.Write(report(3))
+	peer.Write(report(4))
+	if state := sw.State(); state != Connected {
+		t.Errorf("State() = %v, want connected", state)
+	}
+}
+
+func TestDialIPv6(t *testing.T) {
+	tests := []struct{ host, address string }{
+		{"::1", "[::1]:5000"},
+		{"[::1]", "[::1]:5000"},
+		{"fe80::1%eth0", "[fe80::1%eth0]:5000"},
+		{"192.0.2.1", "192.0.2.1:5000"},
+	}
+	for _, tt := range tests {
+		var dialed string
+		dialer := dialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = address
+			client, peer := net.Pipe()
+			go discard(peer)
+			return client, nil
+		})
+
+		sw, err := NewTesmartSwitch(tt.host, "5000", nil, WithDialer(dialer))
+		if err != nil {
+			t.Fatalf("NewTesmartSwitch(%q): %v", tt.host, err)
+		}
+		sw.Close()
+
+		if dialed != tt.address {
+			t.Errorf("host %q dialed %q, want %q", tt.host, dialed, tt.address)
+		}
+		if _, _, err := net.SplitHostPort(dialed); err != nil {
+			t.Errorf("host %q dialed malformed address %q: %v", tt.host, dialed, err)
+		}
+	}
+}
+
+// flakyConn is a net.Conn whose Writes fail with errs, in turn, before it
+// starts writing through.
+type flakyConn struct {
+	net.Conn
+	mu     sync.Mutex
+	errs   []error
+	writes int
+}
+
+func (c *flakyConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	c.writes++
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		c.mu.Unlock()
+		return 0, err
+	}
+	c.mu.Unlock()
+	return c.Conn.Write(b)
+}
+
+func (c *flakyConn) attempts() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes
+}
+
+func TestRetryTimedOutWrite(t *testing.T) {
+	client, peer := net.Pipe()
+	defer peer.Close()
+	writes := readWrites(peer)
+	conn := &flakyConn{Conn: client, errs: []error{os.ErrDeadlineExceeded, os.ErrDeadlineExceeded}}
+	sw, err := NewTesmartSwitchWithConn(conn, nil, WithRetry(2, 10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	start := time.Now()
+	if err := sw.SwitchInput(5); err != nil {
+		t.Fatalf("SwitchInput = %v, want success on the last retry", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("SwitchInput returned after %v, want two retry delays of 10ms", elapsed)
+	}
+
+	want, _ := BuildSwitchInput(5)
+	if got := next(t, writes); !bytes.Equal(got, want) {
+		t.Errorf("wrote % X, want % X", got, want)
+	}
+	if n := conn.attempts(); n != 3 {
+		t.Errorf("%d write attempts, want 3", n)
+	}
+	if state := sw.State(); state != Connected {
+		t.Errorf("State() = %v, want connected", state)
+	}
+}
+
+func TestRetryFailsFast(t *testing.T) {
+	client, peer := net.Pipe()
+	defer peer.Close()
+	go discard(peer)
+	conn := &flakyConn{Conn: client, errs: []error{errors.New("connection reset by peer")}}
+	lost := make(chan error, 1)
+	sw, err := NewTesmartSwitchWithConn(conn, nil, WithRetry(2, time.Hour),
+		WithDisconnectHandler(func(err error) { lost <- err }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	if err := sw.SwitchInput(5); err == nil || isTimeout(err) {
+		t.Fatalf("SwitchInput = %v, want the write error", err)
+	}
+	if n := conn.attempts(); n != 1 {
+		t.Errorf("%d write attempts, want 1", n)
+	}
+	next(t, lost)
+	if state := sw.State(); state != Disconnected {
+		t.Errorf("State() = %v, want disconnected", state)
+	}
+}
+
+func TestShortWrites(t *testing.T) {
+	client, peer := net.Pipe()
+	defer peer.Close()
+	sw, err := NewTesmartSwitchWithConn(chunkConn{client, 3}, nil, WithHealthCheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		frame := make([]byte, frameSize)
+		if _, err := io.ReadFull(peer, frame); err == nil {
+			received <- frame
+		}
+	}()
+
+	if err := sw.SwitchInput(7); err != nil {
+		t.Fatalf("SwitchInput over a conn taking 3 bytes at a time = %v", err)
+	}
+	want, _ := BuildSwitchInput(7)
+	if got := next(t, received); !bytes.Equal(got, want) {
+		t.Errorf("received % X, want % X", got, want)
+	}
+	if got := sw.Stats().BytesWritten; got != frameSize {
+		t.Errorf("Stats().BytesWritten = %d, want %d", got, frameSize)
+	}
+}
+
+func TestCloseLeaksNoGoroutines(t *testing.T) {
+	dialer := &pipeDialer{serve: discard}
+	checkGoroutines(t)
+	t.Cleanup(dialer.wait)
+
+	for range 10 {
+		sw, err := NewTesmartSwitch("192.0.2.1", "5000", nil, WithDialer(dialer),
+			WithHealthCheckInterval(time.Millisecond), WithReconnect(0, 0), WithDebounce(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sw.SwitchInput(2); err != nil {
+			t.Fatal(err)
+		}
+		if err := sw.Close(); err != nil {
+			t.Errorf("Close() = %v", err)
+		}
+	}
+}
+
+// testTLSConfigs returns a server configuration with a self-signed
+// certificate for 127.0.0.1 and 192.0.2.1, and a client configuration that
+// trusts it.
+func testTLSConfigs(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv4(192, 0, 2, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	return server, &tls.Config{RootCAs: roots}
+}
+
+func TestTLS(t *testing.T) {
+	serverConfig, clientConfig := testTLSConfigs(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		frame := make([]byte, frameSize)
+		if _, err := io.ReadFull(conn, frame); err == nil {
+			received <- frame
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	sw, err := NewTesmartSwitch(host, port, nil, WithTLS(clientConfig), WithHealthCheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	if err := sw.SwitchInput(4); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := BuildSwitchInput(4)
+	if got := next(t, received); !bytes.Equal(got, want) {
+		t.Errorf("proxy received % X, want % X", got, want)
+	}
+}
+
+func TestTLSUntrusted(t *testing.T) {
+	serverConfig, _ := testTLSConfigs(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	if sw, err := NewTesmartSwitch(host, port, nil, WithTLS(&tls.Config{})); err == nil {
+		sw.Close()
+		t.Fatal("connected to a proxy with an untrusted certificate")
+	}
+}
+
+func TestTLSWriteTimeoutLosesConnection(t *testing.T) {
+	serverConfig, clientConfig := testTLSConfigs(t)
+	stuck := make(chan struct{})
+	defer close(stuck)
+	dialer := dialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		client, peer := net.Pipe()
+		go func() {
+			defer peer.Close()
+			// Complete the handshake, then stop reading like a stuck
+			// proxy.
+			tls.Server(peer, serverConfig).Handshake()
+			<-stuck
+		}()
+		return client, nil
+	})
+
+	// A retry would wait an hour: the send only returns in time if the
+	// timed out write is not retried.
+	sw, err := NewTesmartSwitch("192.0.2.1", "5000", nil,
+		WithDialer(dialer), WithTLS(clientConfig), WithHealthCheck(false),
+		WithWriteTimeout(10*time.Millisecond), WithRetry(3, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- sw.SwitchInput(2) }()
+	if err := next(t, done); !isTimeout(err) {
+		t.Errorf("SwitchInput to a stuck proxy = %v, want a timeout", err)
+	}
+	if state := sw.State(); state != Disconnected {
+		t.Errorf("State() after the timed out write = %v, want disconnected", state)
+	}
+}
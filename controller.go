@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"context"
+	"net"
+)
+
+// Controller is the set of commands and queries a Switch offers. It is the
+// recommended type for code that drives a switch to depend on: such code can
+// then be tested against NopController, or a fake of its own, instead of a
+// switch on the network. The server packages of this module accept a
+// Controller for that reason.
+type Controller interface {
+	SwitchInput(input int) error
+	SwitchInputContext(ctx context.Context, input int) error
+	SwitchInputAndWait(ctx context.Context, input int) error
+	SwitchNext(ctx context.Context) error
+	SwitchPrevious(ctx context.Context) error
+	GetCurrentInput(ctx context.Context) (int, error)
+	LastKnownInput() (input int, ok bool)
+	Ping(ctx context.Context) error
+
+	MuteBuzzer() error
+	MuteBuzzerContext(ctx context.Context) error
+	UnmuteBuzzer() error
+	UnmuteBuzzerContext(ctx context.Context) error
+	SetBuzzer(enabled bool) error
+	SetBuzzerContext(ctx context.Context, enabled bool) error
+
+	SetLedTimeout(secs int) error
+	SetLedTimeoutContext(ctx context.Context, secs int) error
+	DisableLedTimeout(ctx context.Context) error
+
+	EnableAutoInputDetection() error
+	EnableAutoInputDetectionContext(ctx context.Context) error
+	DisableAutoInputDetection() error
+	DisableAutoInputDetectionContext(ctx context.Context) error
+
+	Model() Model
+	State() ConnectionState
+	RemoteAddr() net.Addr
+	Close() error
+}
+
+var _ Controller = (*Switch)(nil)
+
+// NopController is a Controller that accepts every command and does nothing.
+// It reports a connected Model8 switch on input 1, whatever was sent to it.
+// It is meant as a stand-in in tests and for dependencies that are not used.
+type NopController struct{}
+
+var _ Controller = NopController{}
+
+func (NopController) SwitchInput(int) error                                  { return nil }
+func (NopController) SwitchInputContext(context.Context, int) error          { return nil }
+func (NopController) SwitchInputAndWait(context.Context, int) error          { return nil }
+func (NopController) SwitchNext(context.Context) error                       { return nil }
+func (NopController) SwitchPrevious(context.Context) error                   { return nil }
+func (NopController) GetCurrentInput(context.Context) (int, error)           { return 1, nil }
+func (NopController) LastKnownInput() (int, bool)                            { return 1, true }
+func (NopController) Ping(context.Context) error                             { return nil }
+func (NopController) MuteBuzzer() error                                      { return nil }
+func (NopController) MuteBuzzerContext(context.Context) error                { return nil }
+func (NopController) UnmuteBuzzer() error                                    { return nil }
+func (NopController) UnmuteBuzzerContext(context.Context) error              { return nil }
+func (NopController) SetBuzzer(bool) error                                   { return nil }
+func (NopController) SetBuzzerContext(context.Context, bool) error           { return nil }
+func (NopController) SetLedTimeout(int) error                                { return nil }
+func (NopController) SetLedTimeoutContext(context.Context, int) error        { return nil }
+func (NopController) DisableLedTimeout(context.Context) error                { return nil }
+func (NopController) EnableAutoInputDetection() error                        { return nil }
+func (NopController) EnableAutoInputDetectionContext(context.Context) error  { return nil }
+func (NopController) DisableAutoInputDetection() error                       { return nil }
+func (NopController) DisableAutoInputDetectionContext(context.Context) error { return nil }
+func (NopController) Model() Model                                           { return Model8 }
+func (NopController) State() ConnectionState                                 { return Connected }
+func (NopController) RemoteAddr() net.Addr                                   { return nil }
+func (NopController) Close() error                                           { return nil }
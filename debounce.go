@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"context"
+	"time"
+)
+
+// WithDebounce coalesces SwitchInput calls: a switch is only sent once no
+// further SwitchInput call has arrived for d, and then only for the last
+// requested input. This keeps e.g. a spinning dial from flooding the switch,
+// at the cost of delaying every switch by d. SwitchInput then returns as soon
+// as the request is queued, so write errors are only logged. A switch still
+// waiting when Close is called is sent right away, within
+// debounceFlushTimeout, before the connection is closed.
+func WithDebounce(d time.Duration) Option {
+	return func(t *Switch) {
+		if d > 0 {
+			t.debounce = d
+		}
+	}
+}
+
+// debounceFlushTimeout bounds how long Close waits to send a pending
+// debounced switch.
+const debounceFlushTimeout = time.Second
+
+// queueSwitch hands input to the debounce loop.
+func (t *Switch) queueSwitch(ctx context.Context, input int) error {
+	select {
+	case t.debounced <- input:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.ctx.Done():
+		return ErrNotConnected
+	}
+}
+
+// debounceLoop sends the debounced switches until stopDebounce is called,
+// then sends the pending one, if any, and exits.
+func (t *Switch) debounceLoop() {
+	defer t.wg.Done()
+	defer close(t.debounceDone)
+
+	timer := t.clock.NewTimer(t.debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	pending := 0
+	armed := false
+	for {
+		select {
+		case <-t.ctx.Done():
+			// The constructor failed; there is nothing to flush to.
+			return
+		case <-t.debounceStop:
+			if armed {
+				ctx, cancel := context.WithTimeout(context.Background(), debounceFlushTimeout)
+				t.sendDebounced(ctx, pending)
+				cancel()
+			}
+			return
+		case pending = <-t.debounced:
+			if !timer.Stop() {
+				select {
+				case <-timer.C():
+				default:
+				}
+			}
+			timer.Reset(t.debounce)
+			armed = true
+		case <-timer.C():
+			armed = false
+			t.sendDebounced(t.ctx, pending)
+		}
+	}
+}
+
+func (t *Switch) sendDebounced(ctx context.Context, input int) {
+	if t.redundantSwitch(input) {
+		return
+	}
+	command, err := BuildSwitchInput(input)
+	if err == nil {
+		err = t.send(ctx, command)
+	}
+	if err != nil {
+		t.logger.Printf("Failed to send debounced switch to input %d: %v", input, err)
+	}
+}
+
+// stopDebounce makes the debounce loop flush the pending switch and waits
+// for it to exit. It is called by Close while the connection is still open.
+func (t *Switch) stopDebounce() {
+	if t.debounced == nil {
+		return
+	}
+	close(t.debounceStop)
+	<-t.debounceDone
+}
@@ -0,0 +1,39 @@
+package commands_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	commands "github.com/mfds/tesmart-commands"
+	"github.com/mfds/tesmart-commands/tesmarttest"
+)
+
+func TestDebounceFlushedOnClose(t *testing.T) {
+	srv := tesmarttest.NewServer(8)
+	defer srv.Close()
+
+	sw, err := commands.NewTesmartSwitch(srv.Host(), srv.Port(), nil,
+		commands.WithDebounce(time.Hour), commands.WithHealthCheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.SwitchInput(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.SwitchInput(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := commands.BuildSwitchInput(3)
+	deadline := time.Now().Add(time.Second)
+	for len(srv.Received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := srv.Received(); len(got) != 1 || !bytes.Equal(got[0], want) {
+		t.Errorf("server received % X, want only % X", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// DecodeCommand describes a frame captured on the wire, e.g. with tcpdump or
+// a serial logger, such as "switch to input 3" or "mute buzzer". It
+// understands the commands this package sends and the input reports the
+// switch sends back. Anything else is reported as an error that includes the
+// frame in hex.
+func DecodeCommand(frame []byte) (string, error) {
+	if len(frame) != frameSize {
+		return "", fmt.Errorf("unknown frame %s: want %d bytes, got %d", printHex(frame), frameSize, len(frame))
+	}
+
+	if bytes.HasPrefix(frame, OUTPUT) {
+		input, err := ExtractInput(frame)
+		if err != nil {
+			return "", fmt.Errorf("unknown frame %s: bad checksum", printHex(frame))
+		}
+		return fmt.Sprintf("input %d is active", input), nil
+	}
+
+	if !bytes.Equal(frame[:3], SWITCH_INPUT[:3]) || frame[5] != SWITCH_INPUT[5] {
+		return "", fmt.Errorf("unknown frame %s", printHex(frame))
+	}
+
+	value := frame[4]
+	switch {
+	case frame[3] == SWITCH_INPUT[3] && value >= 1 && value <= maxInputs:
+		return fmt.Sprintf("switch to input %d", value), nil
+	case frame[3] == SET_LED_TIMEOUT[3] && value == 0:
+		return "disable LED timeout", nil
+	case frame[3] == SET_LED_TIMEOUT[3] && value > maxLedTimeout:
+		// Sent to firmware with a longer range, see WithMaxLedTimeout.
+		return fmt.Sprintf("set LED timeout to %ds (beyond the documented 0-%ds)", value, maxLedTimeout), nil
+	case frame[3] == SET_LED_TIMEOUT[3]:
+		return fmt.Sprintf("set LED timeout to %ds", value), nil
+	case bytes.Equal(frame, MUTE_BUZZER):
+		return "mute buzzer", nil
+	case bytes.Equal(frame, UNMUTE_BUZZER):
+		return "unmute buzzer", nil
+	case bytes.Equal(frame, ENABLE_AUTO_INPUT_DETECTION):
+		return "enable auto input detection", nil
+	case bytes.Equal(frame, DISABLE_AUTO_INPUT_DETECTION):
+		return "disable auto input detection", nil
+	case bytes.Equal(frame, GET_CURRENT_INPUT):
+		return "get current input", nil
+	}
+
+	return "", fmt.Errorf("unknown frame %s", printHex(frame))
+}
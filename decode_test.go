@@ -0,0 +1,34 @@
+package commands
+
+import "testing"
+
+func TestDecodeCommand(t *testing.T) {
+	tests := []struct {
+		frame []byte
+		want  string // empty for frames that are not understood
+	}{
+		{[]byte{0xAA, 0xBB, 0x03, 0x01, 0x03, 0xEE}, "switch to input 3"},
+		{[]byte{0xAA, 0xBB, 0x03, 0x01, 0x11, 0xEE}, ""},
+		{[]byte{0xAA, 0xBB, 0x03, 0x03, 0x00, 0xEE}, "disable LED timeout"},
+		{[]byte{0xAA, 0xBB, 0x03, 0x03, 0x1E, 0xEE}, "set LED timeout to 30s"},
+		{[]byte{0xAA, 0xBB, 0x03, 0x03, 0x2D, 0xEE}, "set LED timeout to 45s (beyond the documented 0-30s)"},
+		{[]byte{0xAA, 0xBB, 0x03, 0x03, 0xFF, 0xEE}, "set LED timeout to 255s (beyond the documented 0-30s)"},
+		{[]byte{0xAA, 0xBB, 0x03, 0x02, 0x00, 0xEE}, "mute buzzer"},
+		{[]byte{0xAA, 0xBB, 0x03, 0x10, 0x00, 0xEE}, "get current input"},
+		{[]byte{0xAA, 0xBB, 0x03, 0x11, 0x02, 0x18}, "input 3 is active"},
+		{[]byte{0xAA, 0xBB, 0x03, 0x11, 0x02, 0x19}, ""},
+		{[]byte{0xAA, 0xBB, 0x03, 0x03, 0x0A}, ""},
+	}
+	for _, tt := range tests {
+		got, err := DecodeCommand(tt.frame)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("DecodeCommand(% X) = %q, want an error", tt.frame, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("DecodeCommand(% X) = %q, %v, want %q", tt.frame, got, err, tt.want)
+		}
+	}
+}
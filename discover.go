@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultHost is the address TESmart switches ship with.
+const DefaultHost = "192.168.1.10"
+
+// DefaultDiscoverTimeout is how long Discover waits for each host to answer
+// when called with a timeout of 0 or less.
+const DefaultDiscoverTimeout = 500 * time.Millisecond
+
+// discoverWorkers bounds the number of hosts Discover probes at once.
+const discoverWorkers = 64
+
+// DiscoveredSwitch is a switch found by Discover.
+type DiscoveredSwitch struct {
+	Host  string
+	Port  string
+	Input int // active input it reported
+}
+
+// Discover looks for switches on the local network. TESmart switches do not
+// announce themselves, so Discover probes the factory default address
+// DefaultHost and every address of the IPv4 networks of the machine's
+// interfaces, on DefaultPort. A host counts as a switch when it accepts a
+// connection and answers GET_CURRENT_INPUT with a valid report within
+// timeout; a timeout of 0 or less selects DefaultDiscoverTimeout.
+//
+// Limitations: only DefaultPort is tried; of networks larger than a /24, only
+// the /24 around the interface address is scanned; a switch on another
+// subnet than the machine, as the factory default often is, is found only at
+// DefaultHost and only if it is routable; any device answering the query like
+// a switch is reported as one; and a switch that accepts a single client is
+// not found while another program is connected to it.
+//
+// The result is ordered by address. If ctx is done before every host has
+// been probed, Discover returns the switches found so far and ctx.Err().
+func Discover(ctx context.Context, timeout time.Duration) ([]DiscoveredSwitch, error) {
+	hosts, err := discoverHosts()
+	if err != nil {
+		return nil, err
+	}
+	return discover(ctx, hosts, DefaultPort, timeout)
+}
+
+// discover probes hosts on port, see Discover.
+func discover(ctx context.Context, hosts []netip.Addr, port string, timeout time.Duration) ([]DiscoveredSwitch, error) {
+	if timeout <= 0 {
+		timeout = DefaultDiscoverTimeout
+	}
+
+	var (
+		mu    sync.Mutex
+		found []DiscoveredSwitch
+		wg    sync.WaitGroup
+	)
+	work := make(chan netip.Addr)
+	for i := 0; i < discoverWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range work {
+				if input, ok := probe(ctx, addr.String(), port, timeout); ok {
+					mu.Lock()
+					found = append(found, DiscoveredSwitch{Host: addr.String(), Port: port, Input: input})
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+Hosts:
+	for _, addr := range hosts {
+		select {
+		case work <- addr:
+		case <-ctx.Done():
+			break Hosts
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	sort.Slice(found, func(i, j int) bool {
+		return netip.MustParseAddr(found[i].Host).Less(netip.MustParseAddr(found[j].Host))
+	})
+	return found, ctx.Err()
+}
+
+// discoverHosts returns the addresses Discover probes: DefaultHost and the
+// other addresses of the local IPv4 networks, capped at a /24 each.
+func discoverHosts() ([]netip.Addr, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[netip.Addr]bool{}
+	hosts := []netip.Addr{netip.MustParseAddr(DefaultHost)}
+	seen[hosts[0]] = true
+
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		own, ok := netip.AddrFromSlice(ipnet.IP.To4())
+		if !ok || own.IsLoopback() || own.IsLinkLocalUnicast() {
+			continue
+		}
+		bits, _ := ipnet.Mask.Size()
+		if bits < 24 {
+			bits = 24
+		}
+		prefix := netip.PrefixFrom(own, bits).Masked()
+
+		for addr := prefix.Addr().Next(); prefix.Contains(addr); addr = addr.Next() {
+			if addr == own || seen[addr] || !prefix.Contains(addr.Next()) {
+				// Skip this host and the broadcast address.
+				continue
+			}
+			seen[addr] = true
+			hosts = append(hosts, addr)
+		}
+	}
+	return hosts, nil
+}
+
+// probe asks host:port for its current input and reports whether it answered
+// like a switch.
+func probe(ctx context.Context, host, port string, timeout time.Duration) (int, bool) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return 0, false
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	if _, err := conn.Write(BuildGetCurrentInput()); err != nil {
+		return 0, false
+	}
+
+	frames := newFramer(DefaultProtocol)
+	buf := make([]byte, readBufferSize)
+	for {
+		n, err := conn.Read(buf)
+		complete, _ := frames.push(buf[:n])
+		for _, frame := range complete {
+			if r, err := DefaultProtocol.parseFrame(frame); err == nil {
+				return r.Input, true
+			}
+		}
+		if err != nil {
+			return 0, false
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeSwitchListener accepts connections on a loopback port and answers
+// every query with a report of input.
+func fakeSwitchListener(t *testing.T, input int) (host, port string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				query := make([]byte, frameSize)
+				if _, err := io.ReadFull(conn, query); err == nil && bytes.Equal(query, GET_CURRENT_INPUT) {
+					conn.Write(report(input))
+				}
+			}()
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port
+}
+
+func TestDiscover(t *testing.T) {
+	host, port := fakeSwitchListener(t, 4)
+	hosts := []netip.Addr{netip.MustParseAddr("127.0.0.2"), netip.MustParseAddr(host)}
+
+	want := []DiscoveredSwitch{{Host: host, Port: port, Input: 4}}
+	for _, timeout := range []time.Duration{0, -time.Second, DefaultDiscoverTimeout} {
+		found, err := discover(context.Background(), hosts, port, timeout)
+		if err != nil {
+			t.Errorf("discover with timeout %v: %v", timeout, err)
+		}
+		if !reflect.DeepEqual(found, want) {
+			t.Errorf("discover with timeout %v found %+v, want %+v", timeout, found, want)
+		}
+	}
+}
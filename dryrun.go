@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"io"
+	"sync"
+)
+
+// WithDryRun makes the switch record commands instead of sending them, for
+// testing automation without hardware. Nothing is dialed or opened, the
+// switch reports itself Connected, and neither the receive loop nor the
+// health check runs, so queries such as GetCurrentInput time out. The
+// recorded commands are returned by SentCommands. It has no effect on
+// switches created from an existing connection or transport.
+func WithDryRun() Option {
+	return func(t *Switch) {
+		t.dryRun = true
+	}
+}
+
+// SentCommands returns a copy of every command written by a switch created
+// with WithDryRun, oldest first. It returns nil for other switches.
+func (t *Switch) SentCommands() [][]byte {
+	return t.recorder.commands()
+}
+
+// recorder is the transport of a dry-run switch: it keeps every write and
+// never has anything to read.
+type recorder struct {
+	mu   sync.Mutex
+	sent [][]byte
+}
+
+func (r *recorder) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	r.sent = append(r.sent, append([]byte(nil), p...))
+	r.mu.Unlock()
+	return len(p), nil
+}
+
+func (r *recorder) Close() error {
+	return nil
+}
+
+func (r *recorder) commands() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.sent == nil {
+		return nil
+	}
+	sent := make([][]byte, len(r.sent))
+	for i, command := range r.sent {
+		sent[i] = append([]byte(nil), command...)
+	}
+	return sent
+}
@@ -0,0 +1,40 @@
+package commands
+
+import "errors"
+
+// Errors returned by the package. They are usually wrapped with the command
+// that failed, so compare them with errors.Is.
+var (
+	// ErrInvalidInput is returned when a command argument, such as an input
+	// number or an LED timeout, is out of range.
+	ErrInvalidInput = errors.New("invalid input")
+
+	// ErrInvalidResponse is returned when a frame read from the switch is not
+	// a well-formed input report.
+	ErrInvalidResponse = errors.New("invalid response")
+
+	// ErrChecksum is returned, together with ErrInvalidResponse, for a
+	// report whose checksum does not match its input.
+	ErrChecksum = errors.New("checksum mismatch")
+
+	// ErrNotConnected is returned when a command is issued while there is no
+	// connection to the switch, e.g. while it is reconnecting or after Close.
+	ErrNotConnected = errors.New("not connected")
+
+	// ErrRateLimited is returned when a command exceeds the rate limit set
+	// with WithRateLimit and RateLimit.NoWait is set.
+	ErrRateLimited = errors.New("rate limit exceeded")
+
+	// ErrShortWrite is returned when the transport failed after accepting
+	// only part of a command frame. The connection is then treated as lost,
+	// as the switch has seen a torn frame.
+	ErrShortWrite = errors.New("short write")
+
+	// ErrUnsupported is returned for commands the declared model does not
+	// understand.
+	ErrUnsupported = errors.New("not supported by this model")
+
+	// ErrStateUnknown is returned when reading back a setting that has not
+	// been set through this Switch yet.
+	ErrStateUnknown = errors.New("state unknown")
+)
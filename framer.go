@@ -0,0 +1,66 @@
+package commands
+
+import "bytes"
+
+// frameSize is the length of every frame exchanged with the switch.
+const frameSize = 6
+
+// readBufferSize is how much the receive loop reads at once. Several frames
+// may arrive together, and the framer reassembles any frame split between
+// reads.
+const readBufferSize = 64
+
+// framer splits the byte stream read from the switch into frames. A read may
+// hold several frames or only part of one, so bytes are kept between calls
+// until a frame is complete.
+type framer struct {
+	header  []byte // starts every frame
+	size    int
+	pending []byte
+}
+
+func newFramer(p Protocol) *framer {
+	return &framer{header: p.Header, size: p.frameSize()}
+}
+
+// push adds data read from the switch and returns every frame completed by
+// it, each in its own slice, along with the number of bytes discarded because
+// they did not belong to a frame.
+func (f *framer) push(data []byte) (frames [][]byte, dropped int) {
+	f.pending = append(f.pending, data...)
+
+	for {
+		i := bytes.Index(f.pending, f.header)
+		if i < 0 {
+			// Keep a trailing partial header, the rest of it may arrive
+			// with the next read.
+			keep := f.partialHeader()
+			dropped += len(f.pending) - keep
+			f.pending = append(f.pending[:0], f.pending[len(f.pending)-keep:]...)
+			return frames, dropped
+		}
+
+		dropped += i
+		f.pending = f.pending[i:]
+		if len(f.pending) < f.size {
+			f.pending = append([]byte(nil), f.pending...)
+			return frames, dropped
+		}
+
+		frame := make([]byte, f.size)
+		copy(frame, f.pending)
+		f.pending = f.pending[f.size:]
+		frames = append(frames, frame)
+	}
+}
+
+// partialHeader returns the length of the longest suffix of the pending
+// bytes that is a proper prefix of the header.
+func (f *framer) partialHeader() int {
+	for n := len(f.header) - 1; n > 0; n-- {
+		if bytes.HasSuffix(f.pending, f.header[:n]) {
+			return n
+		}
+	}
+	return 0
+}
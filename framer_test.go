@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFramerSplitFrame(t *testing.T) {
+	frame := report(3)
+	for split := 1; split < len(frame); split++ {
+		f := newFramer(DefaultProtocol)
+
+		frames, dropped := f.push(frame[:split])
+		if len(frames) != 0 || dropped != 0 {
+			t.Errorf("split at %d: first part gave %d frames and dropped %d bytes, want none", split, len(frames), dropped)
+		}
+
+		frames, dropped = f.push(frame[split:])
+		if got, want := fmt.Sprintf("% X", frames), fmt.Sprintf("% X", [][]byte{frame}); got != want || dropped != 0 {
+			t.Errorf("split at %d: got %s, dropped %d bytes, want %s", split, got, dropped, want)
+		}
+	}
+}
+
+func TestFramerPush(t *testing.T) {
+	cat := func(parts ...[]byte) []byte {
+		var b []byte
+		for _, p := range parts {
+			b = append(b, p...)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name    string
+		chunks  [][]byte
+		frames  [][]byte
+		dropped int
+	}{
+		{
+			name:   "two frames in one chunk",
+			chunks: [][]byte{cat(report(1), report(2))},
+			frames: [][]byte{report(1), report(2)},
+		},
+		{
+			name:   "frame split across chunks",
+			chunks: [][]byte{cat(report(1), report(2)[:4]), report(2)[4:]},
+			frames: [][]byte{report(1), report(2)},
+		},
+		{
+			name:    "resync after garbage",
+			chunks:  [][]byte{{0x00, 0xFF, 0xAA}, cat([]byte{0x01}, report(4))},
+			frames:  [][]byte{report(4)},
+			dropped: 4,
+		},
+		{
+			name:   "header split after a frame",
+			chunks: [][]byte{cat(report(1), []byte{0xAA}), report(2)[1:]},
+			frames: [][]byte{report(1), report(2)},
+		},
+	}
+	for _, tt := range tests {
+		f := newFramer(DefaultProtocol)
+		var frames [][]byte
+		dropped := 0
+		for _, chunk := range tt.chunks {
+			got, n := f.push(chunk)
+			frames = append(frames, got...)
+			dropped += n
+		}
+		if got, want := fmt.Sprintf("% X", frames), fmt.Sprintf("% X", tt.frames); got != want {
+			t.Errorf("%s: got frames %s, want %s", tt.name, got, want)
+		}
+		if dropped != tt.dropped {
+			t.Errorf("%s: dropped %d bytes, want %d", tt.name, dropped, tt.dropped)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package commands
+
+import "context"
+
+// InputFuture is the pending result of GetCurrentInputAsync.
+type InputFuture struct {
+	done  chan struct{}
+	input int
+	err   error
+}
+
+// GetCurrentInputAsync starts GetCurrentInput in the background and returns
+// at once, for callers who want to send the query now and collect the answer
+// later. Like GetCurrentInput, the query gives up after DefaultQueryTimeout if
+// ctx has no deadline, and it is abandoned when the switch is closed, so an
+// InputFuture that is never read does not outlive it for long.
+func (t *Switch) GetCurrentInputAsync(ctx context.Context) *InputFuture {
+	f := &InputFuture{done: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(t.ctx, cancel)
+
+	go func() {
+		defer close(f.done)
+		defer cancel()
+		defer stop()
+
+		f.input, f.err = t.GetCurrentInput(ctx)
+	}()
+
+	return f
+}
+
+// Done returns a channel that is closed once the result is available.
+func (f *InputFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Result waits for the query to finish and returns the input or why the
+// query failed, as GetCurrentInput would have.
+func (f *InputFuture) Result() (int, error) {
+	<-f.done
+	return f.input, f.err
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// WithLabels names the inputs of the switch, e.g. {1: "Laptop", 2: "Desktop"},
+// for SwitchInputByLabel and CurrentInputLabel. The map is copied. Empty
+// labels and inputs outside 1-16 are ignored; inputs the declared model does
+// not have are rejected when switching to them.
+func WithLabels(labels map[int]string) Option {
+	return func(t *Switch) {
+		t.labels = make(map[int]string, len(labels))
+		for input, label := range labels {
+			if label != "" && input >= 1 && input <= maxInputs {
+				t.labels[input] = label
+			}
+		}
+	}
+}
+
+// Label returns the label of input set with WithLabels. ok is false if the
+// input has none.
+func (t *Switch) Label(input int) (label string, ok bool) {
+	label, ok = t.labels[input]
+	return label, ok
+}
+
+// SwitchInputByLabel switches to the input labelled label with WithLabels. If
+// several inputs share the label, the lowest one is used. An unknown label,
+// or one naming an input the model does not have, fails with an error
+// wrapping ErrInvalidInput.
+func (t *Switch) SwitchInputByLabel(ctx context.Context, label string) error {
+	input, ok := t.inputForLabel(label)
+	if !ok {
+		return fmt.Errorf("label %q: %w (no input has this label)", label, ErrInvalidInput)
+	}
+	if err := t.checkInput(input); err != nil {
+		return fmt.Errorf("label %q: %w", label, err)
+	}
+	return t.SwitchInputContext(ctx, input)
+}
+
+func (t *Switch) inputForLabel(label string) (int, bool) {
+	inputs := make([]int, 0, len(t.labels))
+	for input, l := range t.labels {
+		if l == label {
+			inputs = append(inputs, input)
+		}
+	}
+	if len(inputs) == 0 {
+		return 0, false
+	}
+	sort.Ints(inputs)
+	return inputs[0], true
+}
+
+// CurrentInputLabel returns the label of the input most recently reported by
+// the switch, see LastKnownInput. ok is false until the first report, or if
+// the input has no label.
+func (t *Switch) CurrentInputLabel() (label string, ok bool) {
+	input, ok := t.LastKnownInput()
+	if !ok {
+		return "", false
+	}
+	return t.Label(input)
+}
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Manager keeps several switches under names of the caller's choosing, so
+// that installations with more than one KVM can address them by name and shut
+// them all down together. It is safe for concurrent use.
+type Manager struct {
+	mu       sync.Mutex
+	switches map[string]*Switch
+	onChange func(name string, state ConnectionState)
+}
+
+// NewManager returns an empty Manager.
+func NewManager() *Manager {
+	return &Manager{switches: make(map[string]*Switch)}
+}
+
+// OnConnectionChange registers a function that is called with the name of the
+// switch for every connection state transition of a managed switch. It
+// replaces any previously registered function.
+func (m *Manager) OnConnectionChange(handler func(name string, state ConnectionState)) {
+	m.mu.Lock()
+	m.onChange = handler
+	m.mu.Unlock()
+}
+
+// Connect creates a switch with NewTesmartSwitch and adds it under name.
+func (m *Manager) Connect(name, host, port string, receiverFunc func([]byte), opts ...Option) (*Switch, error) {
+	if _, ok := m.Switch(name); ok {
+		return nil, fmt.Errorf("switch %q already exists", name)
+	}
+
+	sw, err := NewTesmartSwitch(host, port, receiverFunc, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("switch %q: %w", name, err)
+	}
+
+	if err := m.Add(name, sw); err != nil {
+		sw.Close()
+		return nil, err
+	}
+	return sw, nil
+}
+
+// Add puts sw under name. The manager takes ownership of sw: it is closed by
+// Remove and Close.
+func (m *Manager) Add(name string, sw *Switch) error {
+	m.mu.Lock()
+	if _, ok := m.switches[name]; ok {
+		m.mu.Unlock()
+		return fmt.Errorf("switch %q already exists", name)
+	}
+	m.switches[name] = sw
+	m.mu.Unlock()
+
+	sw.watchState(func(state ConnectionState) {
+		m.mu.Lock()
+		handler := m.onChange
+		current := m.switches[name] == sw
+		m.mu.Unlock()
+
+		if handler != nil && current {
+			handler(name, state)
+		}
+	})
+	return nil
+}
+
+// Remove closes the switch under name and forgets it.
+func (m *Manager) Remove(name string) error {
+	m.mu.Lock()
+	sw, ok := m.switches[name]
+	delete(m.switches, name)
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("switch %q not found", name)
+	}
+	return sw.Close()
+}
+
+// Switch returns the switch under name.
+func (m *Manager) Switch(name string) (*Switch, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sw, ok := m.switches[name]
+	return sw, ok
+}
+
+// Names returns the names of all managed switches in sorted order.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.switches))
+	for name := range m.switches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SwitchInput switches the switch under name to input.
+func (m *Manager) SwitchInput(name string, input int) error {
+	sw, ok := m.Switch(name)
+	if !ok {
+		return fmt.Errorf("switch %q not found", name)
+	}
+
+	if err := sw.SwitchInput(input); err != nil {
+		return fmt.Errorf("switch %q: %w", name, err)
+	}
+	return nil
+}
+
+// Each calls fn for every managed switch, in name order, and returns the
+// errors of all calls joined together.
+func (m *Manager) Each(fn func(name string, sw *Switch) error) error {
+	var errs []error
+	for _, name := range m.Names() {
+		sw, ok := m.Switch(name)
+		if !ok {
+			continue
+		}
+		if err := fn(name, sw); err != nil {
+			errs = append(errs, fmt.Errorf("switch %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes and forgets every managed switch.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	switches := m.switches
+	m.switches = make(map[string]*Switch)
+	m.mu.Unlock()
+
+	var errs []error
+	for name, sw := range switches {
+		if err := sw.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("switch %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
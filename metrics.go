@@ -0,0 +1,33 @@
+package commands
+
+// Metrics receives counts of switch activity, e.g. to export them to a
+// monitoring system; package metrics implements it for Prometheus.
+// Implementations are called from the I/O paths, so they must be safe for
+// concurrent use and must not block. Health check probes are not reported.
+type Metrics interface {
+	// CommandSent is called after a command has been written. command is a
+	// short name such as "switch_input".
+	CommandSent(command string)
+	// SendFailed is called when a command could not be written.
+	SendFailed(command string)
+	// Reconnected is called after automatic reconnection succeeded.
+	Reconnected()
+	// InputReported is called for every input report read from the switch.
+	InputReported(input int)
+}
+
+// WithMetrics reports switch activity to m.
+func WithMetrics(m Metrics) Option {
+	return func(t *Switch) {
+		if m != nil {
+			t.metrics = m
+		}
+	}
+}
+
+type noMetrics struct{}
+
+func (noMetrics) CommandSent(string) {}
+func (noMetrics) SendFailed(string)  {}
+func (noMetrics) Reconnected()       {}
+func (noMetrics) InputReported(int)  {}
@@ -0,0 +1,82 @@
+// Package metrics exports switch activity to Prometheus:
+//
+//	tesmart_commands_sent_total{command}  commands written, by command
+//	tesmart_send_errors_total{command}    commands that failed, by command
+//	tesmart_reconnects_total              successful automatic reconnects
+//	tesmart_current_input                 last input reported by the switch
+//
+// A Collector is both a commands.Metrics and a prometheus.Collector:
+//
+//	c := metrics.NewCollector(prometheus.Labels{"switch": "rack-a"})
+//	prometheus.MustRegister(c)
+//	sw, err := commands.NewTesmartSwitch(host, port, nil, commands.WithMetrics(c))
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector records the activity of one switch.
+type Collector struct {
+	commandsSent *prometheus.CounterVec
+	sendErrors   *prometheus.CounterVec
+	reconnects   prometheus.Counter
+	currentInput prometheus.Gauge
+}
+
+// NewCollector returns a Collector whose metrics all carry labels, which tell
+// switches apart when several are registered.
+func NewCollector(labels prometheus.Labels) *Collector {
+	return &Collector{
+		commandsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "tesmart_commands_sent_total",
+			Help:        "Commands written to the switch, by command.",
+			ConstLabels: labels,
+		}, []string{"command"}),
+		sendErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "tesmart_send_errors_total",
+			Help:        "Commands that could not be written to the switch, by command.",
+			ConstLabels: labels,
+		}, []string{"command"}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "tesmart_reconnects_total",
+			Help:        "Successful automatic reconnects to the switch.",
+			ConstLabels: labels,
+		}),
+		currentInput: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "tesmart_current_input",
+			Help:        "Last input reported by the switch, 0 if none yet.",
+			ConstLabels: labels,
+		}),
+	}
+}
+
+func (c *Collector) CommandSent(command string) {
+	c.commandsSent.WithLabelValues(command).Inc()
+}
+
+func (c *Collector) SendFailed(command string) {
+	c.sendErrors.WithLabelValues(command).Inc()
+}
+
+func (c *Collector) Reconnected() {
+	c.reconnects.Inc()
+}
+
+func (c *Collector) InputReported(input int) {
+	c.currentInput.Set(float64(input))
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.commandsSent.Describe(ch)
+	c.sendErrors.Describe(ch)
+	c.reconnects.Describe(ch)
+	c.currentInput.Describe(ch)
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.commandsSent.Collect(ch)
+	c.sendErrors.Collect(ch)
+	c.reconnects.Collect(ch)
+	c.currentInput.Collect(ch)
+}
@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+)
+
+// Model identifies a TESmart switch model family. The protocol offers no way
+// to ask the switch what it is, so the model has to be declared with
+// WithModel. Until it is, the switch is ModelUnknown.
+type Model int
+
+const (
+	// ModelUnknown accepts inputs 1-16 and sends every command, leaving it
+	// to the switch to ignore what it does not support.
+	ModelUnknown Model = 0
+	// Model8 is the 8-port family, e.g. HKS0801.
+	Model8 Model = 8
+	// Model16 is the 16-port family, e.g. HKS1601.
+	Model16 Model = 16
+
+	// ModelMatrix4x4 and ModelMatrix8x8 are HDMI matrix switches, e.g.
+	// HMA0404, which route any input to each of their outputs with
+	// RouteInputToOutput. They do not understand the commands of the KVM
+	// switches, such as SwitchInput, which are still sent if called. Nor do
+	// they answer the query the health check probes with, so it does not
+	// run for them, see WithHealthCheck.
+	ModelMatrix4x4 Model = 0x0404
+	ModelMatrix8x8 Model = 0x0808
+)
+
+// Inputs returns the number of inputs of the model.
+func (m Model) Inputs() int {
+	switch {
+	case m == ModelUnknown:
+		return 16
+	case m.IsMatrix():
+		return int(m >> 8)
+	}
+	return int(m)
+}
+
+// Outputs returns the number of outputs of the model, 1 unless it is a
+// matrix.
+func (m Model) Outputs() int {
+	if m.IsMatrix() {
+		return int(m & 0xFF)
+	}
+	return 1
+}
+
+// IsMatrix reports whether the model is a matrix switch.
+func (m Model) IsMatrix() bool {
+	return m == ModelMatrix4x4 || m == ModelMatrix8x8
+}
+
+// SupportsAutoInputDetection reports whether the model understands the
+// ENABLE_AUTO_INPUT_DETECTION and DISABLE_AUTO_INPUT_DETECTION commands.
+func (m Model) SupportsAutoInputDetection() bool {
+	return m == Model8 || m == ModelUnknown
+}
+
+// MaxLedTimeout returns the longest LED timeout, in seconds, the model
+// accepts. All known models share the documented range of 0-30 seconds; no
+// model is known to clamp values silently. Firmware with a longer range can
+// be allowed for with WithMaxLedTimeout.
+func (m Model) MaxLedTimeout() int {
+	return maxLedTimeout
+}
+
+func (m Model) String() string {
+	switch m {
+	case ModelUnknown:
+		return "unknown"
+	case Model8, Model16:
+		return fmt.Sprintf("%d-port", int(m))
+	case ModelMatrix4x4, ModelMatrix8x8:
+		return fmt.Sprintf("%dx%d matrix", m.Inputs(), m.Outputs())
+	}
+	return fmt.Sprintf("Model(%d)", int(m))
+}
+
+// Model returns the model declared with WithModel.
+func (t *Switch) Model() Model {
+	return t.model
+}
+
+// GetFirmwareVersion is meant to return the firmware version of the switch.
+// The protocol has no command to read it, and no answer to any known command
+// carries it, so GetFirmwareVersion sends nothing and always fails with an
+// error wrapping ErrUnsupported.
+func (t *Switch) GetFirmwareVersion(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("firmware version: the protocol has no query for it: %w", ErrUnsupported)
+}
+
+// RouteInputToOutput makes output show input, both numbered from 1, on a
+// matrix switch declared with WithModel. Other models, including
+// ModelUnknown, fail with an error wrapping ErrUnsupported.
+//
+// Matrix switches speak an ASCII protocol instead of the binary frames of the
+// KVM switches: routing input 1 to output 2 is "MT00SW0102NT". The frame is
+// sent as is, whatever the Protocol of the switch. The switch answers in
+// ASCII too; the answer is not parsed, and is only seen by WithRawReceiver
+// and WithWireTap.
+func (t *Switch) RouteInputToOutput(ctx context.Context, input, output int) error {
+	if !t.model.IsMatrix() {
+		return fmt.Errorf("route input %d to output %d on the %v model: %w", input, output, t.model, ErrUnsupported)
+	}
+	if input < 1 || input > t.model.Inputs() || output < 1 || output > t.model.Outputs() {
+		return fmt.Errorf("route input %d to output %d: %w (the %v model has inputs 1-%d and outputs 1-%d)",
+			input, output, ErrInvalidInput, t.model, t.model.Inputs(), t.model.Outputs())
+	}
+
+	info := CommandInfo{Command: "route_input", Input: input, Host: t.host, Port: t.port}
+	if err := t.sendFrame(ctx, info, BuildRouteInputToOutput(input, output)); err != nil {
+		return fmt.Errorf("route input %d to output %d: %w", input, output, err)
+	}
+	return nil
+}
+
+// BuildRouteInputToOutput returns the matrix frame that routes input to
+// output, see RouteInputToOutput. Unlike the other Build functions it does
+// not check its arguments, as their range depends on the model; numbers above
+// 99 do not fit the frame.
+func BuildRouteInputToOutput(input, output int) []byte {
+	return []byte(fmt.Sprintf("MT00SW%02d%02dNT", input, output))
+}
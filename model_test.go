@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAutoInputDetectionSupport(t *testing.T) {
+	tests := []struct {
+		model     Model
+		supported bool
+	}{
+		{ModelUnknown, true},
+		{Model8, true},
+		{Model16, false},
+	}
+	for _, tt := range tests {
+		sw, peer := pipeSwitch(t, nil, WithModel(tt.model))
+		writes := readWrites(peer)
+		for name, call := range map[string]func() error{
+			"EnableAutoInputDetection":  sw.EnableAutoInputDetection,
+			"DisableAutoInputDetection": sw.DisableAutoInputDetection,
+		} {
+			err := call()
+			if tt.supported {
+				if err != nil {
+					t.Errorf("%s on model %d = %v", name, tt.model, err)
+				} else {
+					next(t, writes)
+				}
+			}
+			if !tt.supported && !errors.Is(err, ErrUnsupported) {
+				t.Errorf("%s on model %d = %v, want ErrUnsupported", name, tt.model, err)
+			}
+		}
+
+		select {
+		case got := <-writes:
+			t.Errorf("model %d sent % X, want nothing more", tt.model, got)
+		default:
+		}
+	}
+}
+
+func TestSwitchInputPerModel(t *testing.T) {
+	tests := []struct {
+		model Model
+		input int
+		err   string
+	}{
+		{Model8, 0, "switch input 0: invalid input (must be 1-8 on the 8-port model)"},
+		{Model8, 1, ""},
+		{Model8, 8, ""},
+		{Model8, 9, "switch input 9: invalid input (must be 1-8 on the 8-port model)"},
+		{Model8, 16, "switch input 16: invalid input (must be 1-8 on the 8-port model)"},
+		{Model16, 0, "switch input 0: invalid input (must be 1-16 on the 16-port model)"},
+		{Model16, 1, ""},
+		{Model16, 9, ""},
+		{Model16, 16, ""},
+		{Model16, 17, "switch input 17: invalid input (must be 1-16 on the 16-port model)"},
+	}
+	for _, tt := range tests {
+		sw := dryRunSwitch(t, WithModel(tt.model))
+		err := sw.SwitchInput(tt.input)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("SwitchInput(%d) on the %v model = %v", tt.input, tt.model, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err || !errors.Is(err, ErrInvalidInput)):
+			t.Errorf("SwitchInput(%d) on the %v model = %v, want %q", tt.input, tt.model, err, tt.err)
+		}
+	}
+}
+
+func TestSetLedTimeoutPerModel(t *testing.T) {
+	for _, model := range []Model{ModelUnknown, Model8, Model16} {
+		sw := dryRunSwitch(t, WithModel(model))
+		for _, secs := range []int{0, 1, 30} {
+			if err := sw.SetLedTimeout(secs); err != nil {
+				t.Errorf("SetLedTimeout(%d) on the %v model = %v", secs, model, err)
+			}
+		}
+
+		for _, secs := range []int{-1, 31} {
+			want := fmt.Sprintf("set LED timeout %d: invalid input (must be 0-30 on the %v model)", secs, model)
+			if model == ModelUnknown {
+				want = fmt.Sprintf("set LED timeout %d: invalid input (must be 0-30)", secs)
+			}
+			if err := sw.SetLedTimeout(secs); err == nil || err.Error() != want {
+				t.Errorf("SetLedTimeout(%d) on the %v model = %v, want %q", secs, model, err, want)
+			}
+		}
+
+		if sent := sw.SentCommands(); len(sent) != 3 {
+			t.Errorf("the %v model sent % X, want the 3 valid timeouts", model, sent)
+		}
+	}
+}
+
+func TestHealthCheckSkippedForMatrix(t *testing.T) {
+	// A fake clock cannot tell a ticker that is never created from one that
+	// is just late; give a health check ample time to fail instead.
+	sw, peer := pipeSwitch(t, nil, WithModel(ModelMatrix4x4),
+		WithHealthCheck(true), WithHealthCheckInterval(10*time.Millisecond))
+	writes := readWrites(peer)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if err := sw.RouteInputToOutput(context.Background(), 1, 2); err != nil {
+		t.Fatalf("RouteInputToOutput: %v", err)
+	}
+	if got, want := next(t, writes), []byte("MT00SW0102NT"); !bytes.Equal(got, want) {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+	if state := sw.State(); state != Connected {
+		t.Errorf("State() = %v, want connected", state)
+	}
+}
+
+// TestHealthCheckUnanswered makes sure the switch of
+// TestHealthCheckSkippedForMatrix would have been disconnected if it had run
+// the health check.
+func TestHealthCheckUnanswered(t *testing.T) {
+	clock := newFakeClock()
+	sw, peer := pipeSwitch(t, nil, withClock(clock), WithModel(Model8),
+		WithHealthCheck(true), WithHealthCheckInterval(time.Second))
+	writes := readWrites(peer)
+
+	clock.AdvanceToNext(t)
+	if got := next(t, writes); !bytes.Equal(got, GET_CURRENT_INPUT) {
+		t.Fatalf("probe = % X, want % X", got, GET_CURRENT_INPUT)
+	}
+	clock.AdvanceToNext(t)
+
+	next(t, sw.Done())
+	if state := sw.State(); state != Disconnected {
+		t.Errorf("State() = %v, want disconnected", state)
+	}
+}
@@ -0,0 +1,275 @@
+// Package mqttbridge connects a TESmart switch to an MQTT broker. It
+// subscribes to
+//
+//	<prefix>/<id>/input/set      payload: input number, e.g. "3"
+//
+// and publishes, retained,
+//
+//	<prefix>/<id>/input/state    the active input, whenever it changes
+//	<prefix>/<id>/availability   "online" or "offline"
+//
+// It also announces the switch to Home Assistant through MQTT discovery as a
+// select entity, so it appears without any manual configuration.
+package mqttbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	commands "github.com/mfds/tesmart-commands"
+)
+
+// Config describes how the bridge names things on the broker. Zero values
+// select the documented defaults.
+type Config struct {
+	// ID distinguishes switches sharing a broker. Default "switch".
+	ID string
+	// TopicPrefix is the first topic level. Default "tesmart".
+	TopicPrefix string
+	// DiscoveryPrefix is the Home Assistant discovery prefix. Default
+	// "homeassistant".
+	DiscoveryPrefix string
+	// DisableDiscovery stops the bridge from publishing discovery payloads.
+	DisableDiscovery bool
+	// Name is shown in Home Assistant. Default "TESmart <ID>".
+	Name string
+	// Logger receives the errors the bridge has no caller to return to,
+	// such as an invalid payload on the set topic. Default log.Default().
+	Logger *log.Logger
+}
+
+// Bridge relays commands and reports between an MQTT broker and a switch.
+//
+// The bridge learns about reports and connection changes through its
+// HandleResponse and HandleConnectionChange methods, which must be passed to
+// the switch when it is created:
+//
+//	b := mqttbridge.New(opts, cfg)
+//	sw, err := commands.NewTesmartSwitch(host, port, nil,
+//		commands.WithResponseReceiver(b.HandleResponse),
+//		commands.WithConnectionChangeHandler(b.HandleConnectionChange))
+//	...
+//	err = b.Start(sw)
+type Bridge struct {
+	cfg  Config
+	opts *mqtt.ClientOptions
+
+	mu        sync.Mutex
+	client    mqtt.Client
+	sw        commands.Controller
+	lastInput int
+	online    bool
+}
+
+// New returns a bridge that connects to the broker described by opts once
+// started. The bridge sets the will, the connect handler and automatic
+// reconnection on opts; once connected, the broker connection is kept up
+// independently of the switch connection.
+func New(opts *mqtt.ClientOptions, cfg Config) *Bridge {
+	if cfg.ID == "" {
+		cfg.ID = "switch"
+	}
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = "tesmart"
+	}
+	if cfg.DiscoveryPrefix == "" {
+		cfg.DiscoveryPrefix = "homeassistant"
+	}
+	if cfg.Name == "" {
+		cfg.Name = "TESmart " + cfg.ID
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Default()
+	}
+
+	b := &Bridge{cfg: cfg, opts: opts}
+
+	// Only reconnect after a connection was lost: with ConnectRetry, the
+	// first connection would be retried until the broker is reachable, and
+	// Start could block forever.
+	opts.SetAutoReconnect(true)
+	opts.SetConnectRetry(false)
+	opts.SetWill(b.topic("availability"), "offline", 1, true)
+	opts.SetOnConnectHandler(b.onConnect)
+
+	return b
+}
+
+// Start connects to the broker and begins relaying for sw. It returns once
+// the first connection attempt has finished, with its error if it failed, in
+// which case nothing is retried. Connections lost after that are retried in
+// the background.
+func (b *Bridge) Start(sw commands.Controller) error {
+	client := mqtt.NewClient(b.opts)
+
+	b.mu.Lock()
+	b.sw = sw
+	b.client = client
+	b.online = sw.State() == commands.Connected
+	b.mu.Unlock()
+
+	token := client.Connect()
+	token.Wait()
+	return token.Error()
+}
+
+// Stop marks the switch offline and disconnects from the broker. It does not
+// close the switch.
+func (b *Bridge) Stop() {
+	client := b.getClient()
+	if client == nil {
+		return
+	}
+
+	client.Publish(b.topic("availability"), 1, true, "offline").Wait()
+	client.Disconnect(250)
+}
+
+// HandleResponse publishes the input reported by the switch if it changed.
+func (b *Bridge) HandleResponse(r commands.Response) {
+	if r.Type != commands.ResponseInput {
+		return
+	}
+
+	b.mu.Lock()
+	changed := r.Input != b.lastInput
+	b.lastInput = r.Input
+	b.mu.Unlock()
+
+	if changed {
+		b.publishState(r.Input)
+	}
+}
+
+// HandleConnectionChange publishes the availability of the switch.
+func (b *Bridge) HandleConnectionChange(state commands.ConnectionState) {
+	b.mu.Lock()
+	b.online = state == commands.Connected
+	b.mu.Unlock()
+
+	b.publishAvailability()
+}
+
+func (b *Bridge) onConnect(client mqtt.Client) {
+	// Runs on every (re)connect to the broker: subscriptions and retained
+	// messages are restored each time.
+	client.Subscribe(b.topic("input", "set"), 1, b.onSetInput)
+
+	if !b.cfg.DisableDiscovery {
+		b.publishDiscovery()
+	}
+	b.publishAvailability()
+
+	b.mu.Lock()
+	input := b.lastInput
+	b.mu.Unlock()
+	if input != 0 {
+		b.publishState(input)
+	}
+}
+
+func (b *Bridge) onSetInput(_ mqtt.Client, msg mqtt.Message) {
+	input, err := strconv.Atoi(string(msg.Payload()))
+	if err != nil {
+		b.cfg.Logger.Printf("mqttbridge: ignoring %s payload %q: not an input number", msg.Topic(), msg.Payload())
+		return
+	}
+
+	b.mu.Lock()
+	sw := b.sw
+	b.mu.Unlock()
+
+	if sw == nil {
+		return
+	}
+	if err := sw.SwitchInput(input); err != nil {
+		b.cfg.Logger.Printf("mqttbridge: %s: %v", msg.Topic(), err)
+	}
+}
+
+func (b *Bridge) getClient() mqtt.Client {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.client
+}
+
+func (b *Bridge) publishState(input int) {
+	if client := b.getClient(); client != nil {
+		client.Publish(b.topic("input", "state"), 1, true, strconv.Itoa(input))
+	}
+}
+
+func (b *Bridge) publishAvailability() {
+	b.mu.Lock()
+	client := b.client
+	payload := "offline"
+	if b.online {
+		payload = "online"
+	}
+	b.mu.Unlock()
+
+	if client != nil {
+		client.Publish(b.topic("availability"), 1, true, payload)
+	}
+}
+
+// discoveryPayload is the Home Assistant MQTT discovery config of a select
+// entity.
+type discoveryPayload struct {
+	Name              string          `json:"name"`
+	UniqueID          string          `json:"unique_id"`
+	CommandTopic      string          `json:"command_topic"`
+	StateTopic        string          `json:"state_topic"`
+	AvailabilityTopic string          `json:"availability_topic"`
+	Options           []string        `json:"options"`
+	Device            discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model,omitempty"`
+}
+
+func (b *Bridge) publishDiscovery() {
+	b.mu.Lock()
+	model := b.sw.Model()
+	b.mu.Unlock()
+
+	options := make([]string, model.Inputs())
+	for i := range options {
+		options[i] = strconv.Itoa(i + 1)
+	}
+
+	id := "tesmart_" + b.cfg.ID
+	payload, _ := json.Marshal(discoveryPayload{
+		Name:              "Input",
+		UniqueID:          id + "_input",
+		CommandTopic:      b.topic("input", "set"),
+		StateTopic:        b.topic("input", "state"),
+		AvailabilityTopic: b.topic("availability"),
+		Options:           options,
+		Device: discoveryDevice{
+			Identifiers:  []string{id},
+			Name:         b.cfg.Name,
+			Manufacturer: "TESmart",
+			Model:        model.String(),
+		},
+	})
+
+	topic := fmt.Sprintf("%s/select/%s/input/config", b.cfg.DiscoveryPrefix, id)
+	b.getClient().Publish(topic, 1, true, payload)
+}
+
+func (b *Bridge) topic(levels ...string) string {
+	t := b.cfg.TopicPrefix + "/" + b.cfg.ID
+	for _, l := range levels {
+		t += "/" + l
+	}
+	return t
+}
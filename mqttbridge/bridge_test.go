@@ -0,0 +1,110 @@
+package mqttbridge
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	commands "github.com/mfds/tesmart-commands"
+)
+
+func TestStartUnreachableBroker(t *testing.T) {
+	// Find a port nothing listens on.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	opts := mqtt.NewClientOptions().AddBroker("tcp://" + addr).SetConnectTimeout(time.Second)
+	b := New(opts, Config{})
+	sw, _ := pipeSwitch(t)
+
+	done := make(chan error, 1)
+	go func() { done <- b.Start(sw) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Start with no broker succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start with no broker did not return")
+	}
+}
+
+// pipeSwitch returns a switch talking to a net.Pipe, and a channel receiving
+// the frames written to it.
+func pipeSwitch(t *testing.T) (*commands.Switch, <-chan []byte) {
+	t.Helper()
+
+	client, peer := net.Pipe()
+	sw, err := commands.NewTesmartSwitchWithConn(client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		peer.Close()
+		sw.Close()
+	})
+
+	writes := make(chan []byte, 16)
+	go func() {
+		for {
+			frame := make([]byte, 6)
+			if _, err := io.ReadFull(peer, frame); err != nil {
+				return
+			}
+			writes <- frame
+		}
+	}()
+	return sw, writes
+}
+
+// message is an mqtt.Message received on a topic.
+type message struct {
+	mqtt.Message
+	topic   string
+	payload string
+}
+
+func (m message) Topic() string   { return m.topic }
+func (m message) Payload() []byte { return []byte(m.payload) }
+
+func TestSetInput(t *testing.T) {
+	tests := []struct {
+		payload string
+		sent    []byte
+		logged  string
+	}{
+		{payload: "3", sent: []byte{0xAA, 0xBB, 0x03, 0x01, 0x03, 0xEE}},
+		{payload: "three", logged: `ignoring tesmart/switch/input/set payload "three"`},
+		{payload: "17", logged: "switch input 17: invalid input"},
+	}
+	for _, tt := range tests {
+		var logs bytes.Buffer
+		b := New(mqtt.NewClientOptions(), Config{Logger: log.New(&logs, "", 0)})
+		sw, writes := pipeSwitch(t)
+		b.sw = sw
+
+		b.onSetInput(nil, message{topic: b.topic("input", "set"), payload: tt.payload})
+
+		var sent []byte
+		select {
+		case sent = <-writes:
+		case <-time.After(50 * time.Millisecond):
+		}
+		if !bytes.Equal(sent, tt.sent) {
+			t.Errorf("payload %q sent % X, want % X", tt.payload, sent, tt.sent)
+		}
+		if got := logs.String(); tt.logged == "" && got != "" || !strings.Contains(got, tt.logged) {
+			t.Errorf("payload %q logged %q, want %q", tt.payload, got, tt.logged)
+		}
+	}
+}
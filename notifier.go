@@ -0,0 +1,74 @@
+package commands
+
+import "sync"
+
+// notifier runs callbacks one after another on its own goroutine, so that a
+// slow handler delays later notifications but never the caller.
+type notifier struct {
+	mu      sync.Mutex
+	queue   []func()
+	wake    chan struct{}
+	done    chan struct{}
+	started bool
+	closed  bool
+}
+
+// post queues f. It never blocks and does nothing after close.
+func (n *notifier) post(f func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return
+	}
+	if !n.started {
+		n.started = true
+		n.wake = make(chan struct{}, 1)
+		n.done = make(chan struct{})
+		go n.run()
+	}
+
+	n.queue = append(n.queue, f)
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close runs the callbacks already queued and waits for them to finish.
+func (n *notifier) close() {
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+	n.closed = true
+	started := n.started
+	if started {
+		close(n.wake)
+	}
+	n.mu.Unlock()
+
+	if started {
+		<-n.done
+	}
+}
+
+func (n *notifier) run() {
+	defer close(n.done)
+
+	for range n.wake {
+		for {
+			n.mu.Lock()
+			if len(n.queue) == 0 {
+				n.mu.Unlock()
+				break
+			}
+			f := n.queue[0]
+			n.queue = n.queue[1:]
+			n.mu.Unlock()
+
+			f()
+		}
+	}
+}
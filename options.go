@@ -0,0 +1,318 @@
+package commands
+
+import (
+	"crypto/tls"
+	"log"
+	"time"
+)
+
+const (
+	// DefaultDialTimeout bounds how long connecting to the switch may take.
+	DefaultDialTimeout = 5 * time.Second
+
+	// DefaultWriteTimeout bounds how long writing a single command may block.
+	DefaultWriteTimeout = 2 * time.Second
+
+	// DefaultHealthCheckInterval is how often the connection is probed when
+	// no other interval is configured.
+	DefaultHealthCheckInterval = 5 * time.Second
+
+	// DefaultReconnectBackoff and DefaultMaxReconnectBackoff bound the delay
+	// between reconnect attempts when WithReconnect is given zero values.
+	DefaultReconnectBackoff    = 1 * time.Second
+	DefaultMaxReconnectBackoff = 30 * time.Second
+
+	// DefaultReadDeadline is how long a single read from the switch may block
+	// before the receive loop checks whether it should stop.
+	DefaultReadDeadline = 200 * time.Millisecond
+
+	// MinReadDeadline is the shortest read deadline WithReadDeadline accepts.
+	MinReadDeadline = 10 * time.Millisecond
+
+	// DefaultRetryDelay is the pause between send attempts when WithRetry is
+	// given a zero delay.
+	DefaultRetryDelay = 100 * time.Millisecond
+)
+
+// Option configures optional behaviour of a Switch.
+type Option func(*Switch)
+
+// WithModel declares the model of the switch, which determines the valid
+// input range and whether auto input detection is available. Unknown models
+// are ignored.
+func WithModel(m Model) Option {
+	return func(t *Switch) {
+		switch m {
+		case Model8, Model16, ModelMatrix4x4, ModelMatrix8x8:
+			t.model = m
+		}
+	}
+}
+
+// WithMaxLedTimeout raises, or lowers, the longest LED timeout SetLedTimeout
+// accepts to secs seconds. The range of the LED timeout depends on the
+// firmware: TESmart documents 0-30 seconds, which is the default, but some
+// firmware accepts longer timeouts. How a switch treats a value beyond its
+// range is up to the firmware, so only raise the limit for a switch known to
+// support it. Values outside 1-255, the range of the command's value byte,
+// are ignored.
+func WithMaxLedTimeout(secs int) Option {
+	return func(t *Switch) {
+		if secs >= 1 && secs <= 255 {
+			t.ledTimeoutMax = secs
+		}
+	}
+}
+
+// WithLogger sets the logger for debug output of this switch only. Without it
+// the package-level Debug logger is used.
+func WithLogger(l *log.Logger) Option {
+	return func(t *Switch) {
+		t.logger = l
+	}
+}
+
+// WithDebugFromEnv makes the switch log its debug output to stdout if the
+// DEBUG environment variable is set when it is created, which is convenient
+// for programs started from a shell. Without this option the environment is
+// not consulted, so a program embedding the package is never made noisy by
+// it. A logger set with WithLogger takes precedence.
+func WithDebugFromEnv() Option {
+	return func(t *Switch) {
+		t.debugFromEnv = true
+	}
+}
+
+// WithDialTimeout sets how long connecting, and reconnecting, to the switch
+// may take.
+func WithDialTimeout(d time.Duration) Option {
+	return func(t *Switch) {
+		if d > 0 {
+			t.dialTimeout = d
+		}
+	}
+}
+
+// WithWriteTimeout sets how long writing a command may block before it fails.
+// A timed out write means the switch has stopped reading, so the connection is
+// treated as lost. It has no effect on transports without write deadlines.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(t *Switch) {
+		if d > 0 {
+			t.writeTimeout = d
+		}
+	}
+}
+
+// WithReadDeadline sets how long a single read from the switch may block. It
+// bounds how long the receive loop takes to notice that it should stop, e.g.
+// on Close; shorter deadlines react faster but wake up more often when the
+// switch is idle. No data is lost when a read times out. Values below
+// MinReadDeadline are ignored. It has no effect on transports without read
+// deadlines.
+func WithReadDeadline(d time.Duration) Option {
+	return func(t *Switch) {
+		if d >= MinReadDeadline {
+			t.readDeadline = d
+		}
+	}
+}
+
+// WithQueryWindow makes queries for the current input keep collecting frames
+// for d after the first report instead of returning at once, for firmware
+// that answers with more than one frame. See CollectCurrentInput.
+func WithQueryWindow(d time.Duration) Option {
+	return func(t *Switch) {
+		if d > 0 {
+			t.queryWindow = d
+		}
+	}
+}
+
+// WithHealthCheckInterval sets how often the switch is probed with a
+// GET_CURRENT_INPUT query. A probe that is not answered before the next one is
+// due marks the connection as lost.
+func WithHealthCheckInterval(d time.Duration) Option {
+	return func(t *Switch) {
+		if d > 0 {
+			t.healthCheckInterval = d
+		}
+	}
+}
+
+// WithKeepAlive sets the TCP keepalive period of connections to the switch, so
+// that the operating system notices a switch that lost power without closing
+// the connection. A negative period disables keepalives. Without this option
+// the dialer's default applies. The health check, see
+// WithHealthCheckInterval, detects such a switch independently.
+func WithKeepAlive(period time.Duration) Option {
+	return func(t *Switch) {
+		t.keepAlive = period
+	}
+}
+
+// WithHealthCheck turns the periodic health check on or off; it is on by
+// default. Without it no goroutine probes the switch, which suits short-lived
+// programs, but a switch that vanishes without closing the connection is only
+// noticed when a command fails, and LastKnownInput is not kept fresh. Matrix
+// models never run it, as they do not answer the probe.
+func WithHealthCheck(enabled bool) Option {
+	return func(t *Switch) {
+		t.noHealthCheck = !enabled
+	}
+}
+
+// WithSkipRedundantSwitch makes SwitchInput return nil without sending
+// anything when the switch has already reported the requested input, sparing
+// the wire and the buzzer. Only reports read on the current connection count:
+// until the switch has reported its input since connecting, and after every
+// reconnection, switches are sent as usual. A switch reports every change,
+// including ones made on its front panel, so the cached input stays accurate
+// while the connection lasts. With WithDebounce the check is made when the
+// coalesced switch is due.
+func WithSkipRedundantSwitch(skip bool) Option {
+	return func(t *Switch) {
+		t.skipRedundant = skip
+	}
+}
+
+// WithDisconnectHandler registers a function that is called once with the
+// reason when the connection to the switch is lost. It is not called when the
+// switch is closed with Close.
+func WithDisconnectHandler(handler func(error)) Option {
+	return func(t *Switch) {
+		t.disconnectHandler = handler
+	}
+}
+
+// WithConnectionChangeHandler registers a function that is called on every
+// connection state transition. Calls are made in order from a separate
+// goroutine, so a slow handler never stalls the switch; the final
+// Disconnected transition is delivered before Close returns.
+func WithConnectionChangeHandler(handler func(ConnectionState)) Option {
+	return func(t *Switch) {
+		t.connectionChangeHandler = handler
+	}
+}
+
+// WithReconnect enables automatic reconnection after the connection is lost.
+// The delay before the first attempt is backoff and doubles after every failed
+// attempt up to max. Zero values select DefaultReconnectBackoff and
+// DefaultMaxReconnectBackoff. Commands issued while reconnecting fail with
+// ErrNotConnected. Switches created from an existing connection or transport
+// are not reconnected.
+func WithReconnect(backoff, max time.Duration) Option {
+	return func(t *Switch) {
+		t.reconnect = true
+		if backoff > 0 {
+			t.reconnectBackoff = backoff
+		}
+		if max > 0 {
+			t.reconnectMax = max
+		}
+		if t.reconnectMax < t.reconnectBackoff {
+			t.reconnectMax = t.reconnectBackoff
+		}
+	}
+}
+
+// WithReconnectJitter randomizes every reconnect delay, see WithReconnect, by
+// shortening it by up to fraction of it, so that switches that lost their
+// connections together do not all reconnect at the same moments. fraction is
+// clamped to 0-1; 0, the default, disables jitter.
+func WithReconnectJitter(fraction float64) Option {
+	return func(t *Switch) {
+		t.reconnectJitter = min(max(fraction, 0), 1)
+	}
+}
+
+// WithReconnectAttempts limits automatic reconnection, see WithReconnect, to
+// attempts attempts per lost connection; 0, the default, means no limit. When
+// the last one fails the switch stays Disconnected, which is reported to the
+// connection change handler, and Done is closed; only Reconnect can connect
+// it again.
+func WithReconnectAttempts(attempts int) Option {
+	return func(t *Switch) {
+		if attempts >= 0 {
+			t.reconnectAttempts = attempts
+		}
+	}
+}
+
+// WithRetry retries a command up to retries more times, pausing delay between
+// attempts, when writing it times out. Any other write error fails the command
+// at once and marks the connection as lost. A zero delay selects
+// DefaultRetryDelay. Without this option a command is attempted once.
+func WithRetry(retries int, delay time.Duration) Option {
+	return func(t *Switch) {
+		if retries > 0 {
+			t.retries = retries
+		}
+		if delay > 0 {
+			t.retryDelay = delay
+		}
+	}
+}
+
+// WithReconnectHandler registers a function that is called after every
+// reconnect attempt with the attempt number, starting at 1, and the dial error,
+// which is nil once the connection has been re-established.
+func WithReconnectHandler(handler func(attempt int, err error)) Option {
+	return func(t *Switch) {
+		t.reconnectHandler = handler
+	}
+}
+
+// WithDialer replaces the *net.Dialer used to connect, and reconnect, to the
+// switch.
+func WithDialer(d Dialer) Option {
+	return func(t *Switch) {
+		if d != nil {
+			t.dialer = d
+		}
+	}
+}
+
+// WithTLS makes the switch talk TLS over the dialed connection, for switches
+// reached through a TLS-terminating proxy such as stunnel, e.g. across an
+// untrusted network. The switch itself does not speak TLS. If cfg sets no
+// ServerName, the host passed to the constructor is used to verify the
+// certificate. The handshake counts towards the dial timeout. A TLS session
+// cannot survive a write that timed out or was cancelled, so such a write
+// makes the connection count as lost. It has no effect on switches not
+// created by dialing.
+func WithTLS(cfg *tls.Config) Option {
+	return func(t *Switch) {
+		t.tlsConfig = cfg
+	}
+}
+
+// WithRawReceiver registers a function that receives the bytes of every read
+// from the switch as they arrive, before they are split into frames and
+// validated, for debugging the link itself. The slice is only valid during the
+// call.
+func WithRawReceiver(receiver func([]byte)) Option {
+	return func(t *Switch) {
+		t.rawFunc = receiver
+	}
+}
+
+// WithTimedReceiver registers a function that receives every valid report,
+// like the receiver passed to the constructor, along with the time the read
+// that completed it returned, e.g. to correlate switch events with other
+// logs.
+func WithTimedReceiver(receiver func(frame []byte, at time.Time)) Option {
+	return func(t *Switch) {
+		t.timedFunc = receiver
+	}
+}
+
+// WithResponseReceiver registers a function that receives every frame read
+// from the switch as a parsed Response, in addition to the receiver passed to
+// the constructor, which only gets valid reports. WithRawReceiver gets the
+// bytes as read.
+func WithResponseReceiver(receiver func(Response)) Option {
+	return func(t *Switch) {
+		t.responseFunc = receiver
+	}
+}
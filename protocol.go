@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Protocol describes the frame format spoken by a switch, for product lines
+// whose frames differ from the KVM switches this package was written for.
+// Every frame is Header followed by an opcode and a value byte, and ends in
+// Terminator for commands or in the checksum of the value for reports. The
+// opcodes are those of the command variables, e.g. SWITCH_INPUT.
+type Protocol struct {
+	Header       []byte
+	Terminator   byte
+	ReportOpcode byte
+	Checksum     func(value byte) byte
+}
+
+// DefaultProtocol is the frame format of TESmart KVM switches, e.g.
+// AA BB 03 01 02 EE to switch to input 2 and AA BB 03 11 01 17 to report it.
+var DefaultProtocol = Protocol{
+	Header:       []byte{0xAA, 0xBB, 0x03},
+	Terminator:   commandTerminator,
+	ReportOpcode: OUTPUT[3],
+	Checksum: func(value byte) byte {
+		return value + responseChecksumOffset
+	},
+}
+
+// WithProtocol makes the switch use p on the wire instead of DefaultProtocol.
+// Frames passed to the receiver and returned by SentCommands are in the
+// format of p. A p without Header or Checksum is ignored.
+func WithProtocol(p Protocol) Option {
+	return func(t *Switch) {
+		if len(p.Header) > 0 && p.Checksum != nil {
+			p.Header = append([]byte(nil), p.Header...)
+			t.protocol = p
+		}
+	}
+}
+
+// frameSize returns the length of every frame of p.
+func (p Protocol) frameSize() int {
+	return len(p.Header) + 3
+}
+
+// encode converts command, built for DefaultProtocol like the command
+// variables, to the frame format of p.
+func (p Protocol) encode(command []byte) []byte {
+	frame := make([]byte, 0, p.frameSize())
+	frame = append(frame, p.Header...)
+	return append(frame, command[3], command[4], p.Terminator)
+}
+
+// parseFrame classifies frame, read from a switch speaking p. The error
+// says why a frame is not a valid report.
+func (p Protocol) parseFrame(frame []byte) (Response, error) {
+	r := Response{Type: ResponseUnknown, Raw: frame}
+
+	n := len(p.Header)
+	switch {
+	case len(frame) != p.frameSize():
+		return r, fmt.Errorf("%w %s: %d bytes, want %d", ErrInvalidResponse, printHex(frame), len(frame), p.frameSize())
+	case !bytes.Equal(frame[:n], p.Header):
+		return r, fmt.Errorf("%w %s: header is not %s", ErrInvalidResponse, printHex(frame), printHex(p.Header))
+	case frame[n] != p.ReportOpcode:
+		return r, fmt.Errorf("%w %s: opcode %02X is not a report", ErrInvalidResponse, printHex(frame), frame[n])
+	}
+
+	r.Checksum = frame[n+2]
+	r.ExpectedChecksum = p.Checksum(frame[n+1])
+	if r.Checksum != r.ExpectedChecksum {
+		return r, fmt.Errorf("%w %s: %w: got %02X, want %02X", ErrInvalidResponse, printHex(frame), ErrChecksum, r.Checksum, r.ExpectedChecksum)
+	}
+
+	r.Type = ResponseInput
+	r.Input = int(frame[n+1]) + 1 // input is zero based
+	return r, nil
+}
@@ -0,0 +1,325 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultQueryTimeout bounds synchronous queries whose context has no
+	// deadline.
+	DefaultQueryTimeout = 2 * time.Second
+
+	// confirmPollInterval is how often SwitchInputAndWait asks for the
+	// current input while it has not seen the requested one.
+	confirmPollInterval = 500 * time.Millisecond
+)
+
+// GetCurrentInput asks the switch for its active input and waits for the
+// report. It returns the 1-based input number. If ctx has no deadline the
+// query gives up after DefaultQueryTimeout. With WithQueryWindow, the last
+// input reported within the window is returned.
+//
+// The report is also delivered to the receiver. Concurrent callers each see
+// every report, so one caller cannot consume another's answer.
+func (t *Switch) GetCurrentInput(ctx context.Context) (int, error) {
+	responses, err := t.CollectCurrentInput(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	input := 0
+	for _, r := range responses {
+		if r.Type == ResponseInput {
+			input = r.Input
+		}
+	}
+	return input, nil
+}
+
+// CollectCurrentInput asks the switch for its active input and returns every
+// frame read from then on until the first input report, plus those read
+// within the window set with WithQueryWindow after it. The result therefore
+// always contains at least one ResponseInput. If ctx has no deadline the
+// query gives up after DefaultQueryTimeout; once a report has arrived, ctx
+// being done only ends the window early.
+//
+// The switches this package was written against answer a query with a
+// single frame, so without a window the result is that frame, possibly
+// preceded by unrelated ones. The window is for firmware that answers with
+// several frames.
+func (t *Switch) CollectCurrentInput(ctx context.Context) ([]Response, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultQueryTimeout)
+		defer cancel()
+	}
+
+	frames, unsubscribe := t.subscribe()
+	defer unsubscribe()
+
+	if err := t.send(ctx, BuildGetCurrentInput()); err != nil {
+		return nil, fmt.Errorf("get current input: %w", err)
+	}
+
+	var responses []Response
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("get current input: no report from switch: %w", ctx.Err())
+		case r := <-frames:
+			responses = append(responses, r)
+			if r.Type == ResponseInput {
+				return t.collectWindow(ctx, frames, responses), nil
+			}
+		}
+	}
+}
+
+// collectWindow appends the frames read within the query window.
+func (t *Switch) collectWindow(ctx context.Context, frames <-chan Response, responses []Response) []Response {
+	if t.queryWindow <= 0 {
+		return responses
+	}
+
+	timer := t.clock.NewTimer(t.queryWindow)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return responses
+		case <-timer.C():
+			return responses
+		case r := <-frames:
+			responses = append(responses, r)
+		}
+	}
+}
+
+// Ping checks that the switch is reachable and answering by asking for its
+// current input. It returns nil once a valid report arrives. If ctx has no
+// deadline it gives up after DefaultQueryTimeout. Ping is safe to call while
+// other commands are in flight.
+func (t *Switch) Ping(ctx context.Context) error {
+	if _, err := t.GetCurrentInput(ctx); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	return nil
+}
+
+// reportsBuffer is how many reports Reports buffers for a slow reader.
+const reportsBuffer = 16
+
+// Reports returns a channel that receives the input number every time the
+// switch reports its active input, whether in answer to a query, to
+// acknowledge a switch or because the input changed on the device. If the
+// reader falls behind, the oldest reports are dropped. The channel is closed
+// by Close. All callers share the same channel, so each report is received
+// by only one of them.
+func (t *Switch) Reports() <-chan int {
+	return t.reports
+}
+
+// DrainReports discards the reports buffered in the Reports channel and
+// returns how many there were, so that the next report received is a fresh
+// one. The synchronous queries need no draining: GetCurrentInput and
+// SwitchInputAndWait only consider frames read after they were called and do
+// not consume from Reports.
+func (t *Switch) DrainReports() int {
+	n := 0
+	for {
+		select {
+		case _, ok := <-t.reports:
+			if !ok {
+				return n
+			}
+			n++
+		default:
+			return n
+		}
+	}
+}
+
+func (t *Switch) report(input int) {
+	for {
+		select {
+		case t.reports <- input:
+			return
+		default:
+		}
+
+		// Full: make room by dropping the oldest report.
+		select {
+		case <-t.reports:
+		default:
+		}
+	}
+}
+
+// LastKnownInput returns the input most recently reported by the switch
+// without querying it. ok is false until the first report has been read. The
+// health check keeps the value fresh while the switch is connected. It never
+// blocks, so it is cheap to call from any goroutine, e.g. to render a UI.
+func (t *Switch) LastKnownInput() (input int, ok bool) {
+	input = int(t.lastInput.Load())
+	return input, input != 0
+}
+
+// SwitchInputAndWait switches to input and waits until the switch reports it
+// as active. The switch normally acknowledges a switch command with a report;
+// if it does not, the current input is queried periodically. If ctx has no
+// deadline the wait gives up after DefaultQueryTimeout. When the switch kept
+// reporting another input, the error says which.
+func (t *Switch) SwitchInputAndWait(ctx context.Context, input int) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultQueryTimeout)
+		defer cancel()
+	}
+
+	frames, unsubscribe := t.subscribe()
+	defer unsubscribe()
+
+	if err := t.SwitchInputContext(ctx, input); err != nil {
+		return err
+	}
+
+	poll := t.clock.NewTicker(confirmPollInterval)
+	defer poll.Stop()
+
+	reported := 0
+	for {
+		select {
+		case <-ctx.Done():
+			if reported != 0 {
+				return fmt.Errorf("switch input %d: switch reports input %d: %w", input, reported, ctx.Err())
+			}
+			return fmt.Errorf("switch input %d: no report from switch: %w", input, ctx.Err())
+		case <-poll.C():
+			if err := t.send(ctx, BuildGetCurrentInput()); err != nil && ctx.Err() == nil {
+				return fmt.Errorf("switch input %d: get current input: %w", input, err)
+			}
+		case r := <-frames:
+			if r.Type == ResponseInput {
+				if r.Input == input {
+					return nil
+				}
+				reported = r.Input
+			}
+		}
+	}
+}
+
+// WaitForInput blocks until input is active and returns ctx.Err() if ctx is
+// done first. It returns at once if LastKnownInput already is input and was
+// reported on the current connection; otherwise, e.g. after a reconnect, it
+// waits for a report, without asking for one. Any number of
+// goroutines may wait, for the same or different inputs, and none of them
+// consume from Reports.
+func (t *Switch) WaitForInput(ctx context.Context, input int) error {
+	frames, unsubscribe := t.subscribe()
+	defer unsubscribe()
+
+	if current, ok := t.LastKnownInput(); ok && current == input && t.inputFresh.Load() {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case r := <-frames:
+			if r.Type == ResponseInput && r.Input == input {
+				return nil
+			}
+		}
+	}
+}
+
+// SwitchNext switches to the input after the active one, wrapping around from
+// the last input of the model to the first. The active input is taken from
+// LastKnownInput, or queried if none has been reported yet.
+func (t *Switch) SwitchNext(ctx context.Context) error {
+	return t.switchRelative(ctx, 1)
+}
+
+// SwitchPrevious switches to the input before the active one, wrapping around
+// from the first input to the last. See SwitchNext.
+func (t *Switch) SwitchPrevious(ctx context.Context) error {
+	return t.switchRelative(ctx, -1)
+}
+
+func (t *Switch) switchRelative(ctx context.Context, step int) error {
+	current, ok := t.LastKnownInput()
+	if !ok {
+		var err error
+		if current, err = t.GetCurrentInput(ctx); err != nil {
+			return err
+		}
+	}
+
+	n := t.model.Inputs()
+	next := ((current-1+step)%n+n)%n + 1
+	return t.SwitchInputContext(ctx, next)
+}
+
+// subscribe registers a channel that receives every frame read from the
+// switch, parsed and with a copy of the frame as Raw, until the returned
+// function is called. Frames are dropped for subscribers that fall behind.
+func (t *Switch) subscribe() (<-chan Response, func()) {
+	ch := make(chan Response, 8)
+
+	t.subMu.Lock()
+	if t.subscribers == nil {
+		t.subscribers = make(map[chan Response]struct{})
+	}
+	t.subscribers[ch] = struct{}{}
+	t.subMu.Unlock()
+
+	return ch, func() {
+		t.subMu.Lock()
+		delete(t.subscribers, ch)
+		t.subMu.Unlock()
+	}
+}
+
+// dispatch hands a frame read from the switch at the given time to the
+// subscribers and the receivers.
+func (t *Switch) dispatch(frame []byte, at time.Time) {
+	r, err := t.protocol.parseFrame(append([]byte(nil), frame...))
+	r.At = at
+
+	t.subMu.Lock()
+	for ch := range t.subscribers {
+		sub := r
+		sub.Raw = append([]byte(nil), frame...)
+		select {
+		case ch <- sub:
+		default:
+		}
+	}
+	t.subMu.Unlock()
+
+	if err != nil {
+		t.logger.Printf("Not passing invalid frame to the receiver: %v", err)
+	} else {
+		if t.receiverFunc != nil {
+			t.receiverFunc(frame)
+		}
+		if t.timedFunc != nil {
+			t.timedFunc(frame, at)
+		}
+
+		t.lastInput.Store(int64(r.Input))
+		t.inputFresh.Store(true)
+
+		t.metrics.InputReported(r.Input)
+		t.report(r.Input)
+	}
+
+	if t.responseFunc != nil {
+		t.responseFunc(r)
+	}
+}
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLastKnownInputConcurrent(t *testing.T) {
+	sw, peer := pipeSwitch(t, nil)
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				if input, ok := sw.LastKnownInput(); ok && (input < 1 || input > 8) {
+					t.Errorf("LastKnownInput() = %d, want 1-8", input)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := range 200 {
+		peer.Write(report(i%8 + 1))
+	}
+
+	// The last report is handled shortly after it is read.
+	deadline := time.Now().Add(time.Second)
+	for input, _ := sw.LastKnownInput(); input != 8 && time.Now().Before(deadline); input, _ = sw.LastKnownInput() {
+		time.Sleep(time.Millisecond)
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	if input, ok := sw.LastKnownInput(); !ok || input != 8 {
+		t.Errorf("LastKnownInput() = %d, %v after the last report, want 8, true", input, ok)
+	}
+}
+
+// answerQueries plays a switch that answers every query with answer.
+func answerQueries(t *testing.T, peer net.Conn, answer []byte) {
+	t.Helper()
+	writes := readWrites(peer)
+	go func() {
+		for query := range writes {
+			if bytes.Equal(query, GET_CURRENT_INPUT) {
+				peer.Write(answer)
+			}
+		}
+	}()
+}
+
+func TestCollectCurrentInput(t *testing.T) {
+	garbled := report(1)
+	garbled[5] = 0
+	answer := append(append(garbled, report(2)...), report(3)...)
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		inputs []int // of the responses, 0 for those that are not reports
+		input  int
+	}{
+		{"single frame", nil, []int{0, 2}, 2},
+		{"query window", []Option{WithQueryWindow(200 * time.Millisecond)}, []int{0, 2, 3}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each query gets a switch of its own, so that frames left
+			// over from one answer cannot end up in the next.
+			sw, peer := pipeSwitch(t, nil, tt.opts...)
+			answerQueries(t, peer, answer)
+			responses, err := sw.CollectCurrentInput(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var inputs []int
+			for _, r := range responses {
+				inputs = append(inputs, r.Input)
+			}
+			if fmt.Sprint(inputs) != fmt.Sprint(tt.inputs) {
+				t.Errorf("CollectCurrentInput() returned inputs %v, want %v", inputs, tt.inputs)
+			}
+
+			sw, peer = pipeSwitch(t, nil, tt.opts...)
+			answerQueries(t, peer, answer)
+			input, err := sw.GetCurrentInput(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if input != tt.input {
+				t.Errorf("GetCurrentInput() = %d, want %d", input, tt.input)
+			}
+		})
+	}
+}
+
+func TestWaitForInputAfterReconnect(t *testing.T) {
+	peers := make(chan net.Conn, 2)
+	dialer := dialerFunc(func(ctx context.Context, network, address string) (net.Conn, error) {
+		client, peer := net.Pipe()
+		peers <- peer
+		return client, nil
+	})
+	sw, err := NewTesmartSwitch("192.0.2.1", "5000", nil, WithDialer(dialer), WithHealthCheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sw.Close() })
+
+	// The second write only completes once the report is handled.
+	peer := next(t, peers)
+	peer.Write(report(3))
+	peer.Write([]byte{0x00})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := sw.WaitForInput(ctx, 3); err != nil {
+		t.Fatalf("WaitForInput of the reported input = %v, want nil", err)
+	}
+
+	if err := sw.Reconnect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	peer = next(t, peers)
+
+	// The report is from the old connection, so it no longer counts.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := sw.WaitForInput(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitForInput after a reconnect = %v, want context.DeadlineExceeded", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- sw.WaitForInput(context.Background(), 3) }()
+	peer.Write(report(3))
+	if err := next(t, done); err != nil {
+		t.Errorf("WaitForInput once reported again = %v, want nil", err)
+	}
+}
+
+func TestQueriesIgnoreCommandVariables(t *testing.T) {
+	// A caller scribbling over the exported variable must not corrupt the
+	// frames the Switch sends itself.
+	saved := GET_CURRENT_INPUT[4]
+	GET_CURRENT_INPUT[4] = 0x55
+	defer func() { GET_CURRENT_INPUT[4] = saved }()
+
+	sw, peer := pipeSwitch(t, nil)
+	writes := readWrites(peer)
+
+	result := make(chan int, 1)
+	go func() {
+		input, _ := sw.GetCurrentInput(context.Background())
+		result <- input
+	}()
+	if got := fmt.Sprintf("% X", next(t, writes)); got != "AA BB 03 10 00 EE" {
+		t.Errorf("GetCurrentInput wrote %s, want AA BB 03 10 00 EE", got)
+	}
+	peer.Write(report(2))
+	if input := next(t, result); input != 2 {
+		t.Errorf("GetCurrentInput = %d, want 2", input)
+	}
+}
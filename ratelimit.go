@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultCommandRate is the rate, in commands per second, used by
+// WithRateLimit when RateLimit.Rate is zero. TESmart does not document how
+// fast the switches accept commands; this is a conservative value that has
+// no noticeable effect on interactive use.
+const DefaultCommandRate = 5
+
+// RateLimit configures WithRateLimit.
+type RateLimit struct {
+	// Rate is how many commands per second are sent on average.
+	Rate float64
+	// Burst is how many commands may be sent at once after an idle period,
+	// at least 1.
+	Burst int
+	// NoWait makes commands over the limit fail with ErrRateLimited instead
+	// of waiting for their turn.
+	NoWait bool
+}
+
+// WithRateLimit smooths bursts of commands, e.g. from automation, which the
+// switch may not keep up with. Every command written counts against a token
+// bucket refilled at limit.Rate; health check probes are exempt. A command
+// over the limit waits until it may be sent, or until its context is done,
+// unless limit.NoWait is set.
+func WithRateLimit(limit RateLimit) Option {
+	return func(t *Switch) {
+		if limit.Rate <= 0 {
+			limit.Rate = DefaultCommandRate
+		}
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+		t.limiter = &limiter{
+			rate:   limit.Rate,
+			burst:  float64(limit.Burst),
+			tokens: float64(limit.Burst),
+			noWait: limit.NoWait,
+		}
+	}
+}
+
+// limiter is a token bucket. tokens may go negative, which means commands are
+// waiting for tokens that have not been refilled yet.
+type limiter struct {
+	clock  clock   // set by newSwitch
+	rate   float64 // tokens per second
+	burst  float64
+	noWait bool
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// refillLocked adds the tokens accrued since the last call. l.mu must be held.
+func (l *limiter) refillLocked(now time.Time) {
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+// wait takes n tokens, one per command, waiting for them unless l.noWait is
+// set.
+func (l *limiter) wait(ctx context.Context, n int) error {
+	need := float64(n)
+	l.mu.Lock()
+	l.refillLocked(l.clock.Now())
+	if l.tokens >= need {
+		l.tokens -= need
+		l.mu.Unlock()
+		return nil
+	}
+	if l.noWait {
+		l.mu.Unlock()
+		return fmt.Errorf("%w (%g commands/s)", ErrRateLimited, l.rate)
+	}
+
+	// Reserve the tokens and sleep until they have been refilled.
+	delay := time.Duration((need - l.tokens) / l.rate * float64(time.Second))
+	l.tokens -= need
+	l.mu.Unlock()
+
+	timer := l.clock.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		// Give the reservation back for the commands behind us.
+		l.release(n)
+		return ctx.Err()
+	}
+}
+
+// release gives back n tokens taken by wait for commands that were not sent.
+func (l *limiter) release(n int) {
+	l.mu.Lock()
+	l.tokens += float64(n)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.mu.Unlock()
+}
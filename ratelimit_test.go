@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitNoWait(t *testing.T) {
+	clock := newFakeClock()
+	sw, peer := pipeSwitch(t, nil, withClock(clock),
+		WithRateLimit(RateLimit{Rate: 1, Burst: 1, NoWait: true}))
+	writes := readWrites(peer)
+
+	if err := sw.SwitchInput(1); err != nil {
+		t.Fatalf("first SwitchInput: %v", err)
+	}
+	next(t, writes)
+
+	if err := sw.SwitchInput(2); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("second SwitchInput = %v, want ErrRateLimited", err)
+	}
+
+	clock.Advance(time.Second)
+	if err := sw.SwitchInput(2); err != nil {
+		t.Fatalf("SwitchInput after refill: %v", err)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	clock := newFakeClock()
+	sw, peer := pipeSwitch(t, nil, withClock(clock), WithRateLimit(RateLimit{Rate: 2, Burst: 1}))
+	writes := readWrites(peer)
+
+	if err := sw.SwitchInput(1); err != nil {
+		t.Fatal(err)
+	}
+	next(t, writes)
+
+	done := make(chan error, 1)
+	go func() { done <- sw.SwitchInput(2) }()
+
+	if d := clock.AdvanceToNext(t); d != 500*time.Millisecond {
+		t.Errorf("second command waited %v, want 500ms", d)
+	}
+	if err := next(t, done); err != nil {
+		t.Fatal(err)
+	}
+	if got := next(t, writes); got[4] != 2 {
+		t.Errorf("second command = % X, want a switch to input 2", got)
+	}
+}
+
+func TestRateLimitExemptsHealthCheck(t *testing.T) {
+	clock := newFakeClock()
+	sw, peer := pipeSwitch(t, nil, withClock(clock),
+		WithRateLimit(RateLimit{Rate: 1, Burst: 1, NoWait: true}),
+		WithHealthCheck(true), WithHealthCheckInterval(5*time.Second))
+	writes := readWrites(peer)
+
+	// Take the only token just before the probe is due.
+	if d := clock.next(t); d != 5*time.Second {
+		t.Fatalf("health check due in %v, want 5s", d)
+	}
+	clock.Advance(5*time.Second - time.Millisecond)
+	if err := sw.SwitchInput(1); err != nil {
+		t.Fatal(err)
+	}
+	next(t, writes)
+
+	clock.Advance(time.Millisecond)
+	if got := next(t, writes); !bytes.Equal(got, GET_CURRENT_INPUT) {
+		t.Fatalf("wrote % X, want the probe % X", got, GET_CURRENT_INPUT)
+	}
+	if state := sw.State(); state != Connected {
+		t.Errorf("State() = %v after the probe, want connected", state)
+	}
+}
+
+func TestRateLimitQueuedDeadline(t *testing.T) {
+	sw, peer := pipeSwitch(t, nil, WithRateLimit(RateLimit{Rate: 1, Burst: 1}))
+	writes := readWrites(peer)
+
+	if err := sw.SwitchInput(1); err != nil {
+		t.Fatal(err)
+	}
+	next(t, writes)
+
+	// The second command waits a second for the next token.
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := make(chan error, 1)
+	go func() { waiting <- sw.SwitchInputContext(ctx, 2) }()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		sw.limiter.mu.Lock()
+		reserved := sw.limiter.tokens < 0
+		sw.limiter.mu.Unlock()
+		if reserved {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The third, queued behind it, must still give up by its deadline.
+	short, cancelShort := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelShort()
+	start := time.Now()
+	if err := sw.SwitchInputContext(short, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued SwitchInputContext = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("queued SwitchInputContext returned after %v, want about 100ms", elapsed)
+	}
+
+	cancel()
+	if err := next(t, waiting); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled SwitchInputContext = %v, want context.Canceled", err)
+	}
+}
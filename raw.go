@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+)
+
+// SendRaw writes frame to the switch as is, for experimenting with frames the
+// package has no builder for. It goes through the same lock, rate limit,
+// deadlines and retries as every other command, but frame is not re-encoded:
+// it must already be in the wire format of the switch, i.e. the Protocol set
+// with WithProtocol, and have its frame size (6 bytes by default). A frame of
+// another length fails with an error wrapping ErrInvalidInput.
+//
+// Use it with care. The switch does not acknowledge unknown frames, so there
+// is no telling whether it understood one, and a malformed frame may
+// desynchronize its parser or change settings nobody knows how to change
+// back. SendRaw does not update the state tracked by the Switch, such as
+// GetBuzzerState, and it is counted as "raw" in Metrics.
+func (t *Switch) SendRaw(ctx context.Context, frame []byte) error {
+	if size := t.protocol.frameSize(); len(frame) != size {
+		return fmt.Errorf("send raw %s: %w (%d bytes, want %d)", printHex(frame), ErrInvalidInput, len(frame), size)
+	}
+
+	if err := t.sendFrame(ctx, CommandInfo{Command: "raw", Host: t.host, Port: t.port}, frame); err != nil {
+		return fmt.Errorf("send raw %s: %w", printHex(frame), err)
+	}
+	return nil
+}
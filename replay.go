@@ -0,0 +1,173 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithCapture writes everything read from the switch to w, to be replayed
+// later with NewTesmartSwitchFromReplay. Each read is a line holding the time
+// since the first read and the bytes in hex:
+//
+//	0s AA BB 03 11 02 18
+//	1.503s AA BB 03 11 00 16
+//
+// Bytes are written as read, before they are split into frames, so that
+// framing problems are captured too. When replaying, blank lines and lines
+// starting with # are ignored, so captures may be annotated by hand. Write
+// errors are logged and otherwise ignored.
+func WithCapture(w io.Writer) Option {
+	return func(t *Switch) {
+		if w != nil {
+			t.capture = &capture{w: w}
+		}
+	}
+}
+
+type capture struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+}
+
+// write records data, read at now.
+func (c *capture) write(now time.Time, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.start.IsZero() {
+		c.start = now
+	}
+	_, err := fmt.Fprintf(c.w, "%v % X\n", now.Sub(c.start), data)
+	return err
+}
+
+// WithRecordedTiming makes a switch created with NewTesmartSwitchFromReplay
+// deliver the captured reads at the pace they were recorded instead of as
+// fast as possible. It has no effect on other switches.
+func WithRecordedTiming() Option {
+	return func(t *Switch) {
+		t.recordedTiming = true
+	}
+}
+
+// NewTesmartSwitchFromReplay creates a switch that reads from a capture, as
+// written by WithCapture, instead of a live connection. The captured bytes go
+// through the same framing, parsing and dispatch as those of a real switch,
+// to reproduce problems with them. Commands are accepted and discarded.
+//
+// The health check is disabled. When the capture is exhausted, or a line of
+// it cannot be parsed, the connection is lost as if the switch had hung up:
+// Done is closed and the disconnect handler gets the reason, which is io.EOF
+// at the end of the capture.
+func NewTesmartSwitchFromReplay(r io.Reader, receiverFunc func([]byte), opts ...Option) (*Switch, error) {
+	opts = append([]Option{WithHealthCheck(false)}, opts...)
+	t := newSwitch(receiverFunc, opts)
+
+	err := t.attach(&replayer{
+		lines:  bufio.NewScanner(r),
+		timed:  t.recordedTiming,
+		clock:  t.clock,
+		closed: make(chan struct{}),
+	})
+	if err != nil {
+		t.cancel()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// replayer is the transport of a replaying switch. Reads return the captured
+// reads in order.
+type replayer struct {
+	lines   *bufio.Scanner
+	line    int
+	timed   bool
+	clock   clock
+	start   time.Time
+	pending []byte
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (r *replayer) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		at, data, err := r.next()
+		if err != nil {
+			return 0, err
+		}
+
+		if r.timed {
+			now := r.clock.Now()
+			if r.start.IsZero() {
+				r.start = now
+			}
+			timer := r.clock.NewTimer(r.start.Add(at).Sub(now))
+			select {
+			case <-r.closed:
+				timer.Stop()
+				return 0, io.EOF
+			case <-timer.C():
+			}
+		}
+		r.pending = data
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// next returns the next captured read and when it happened.
+func (r *replayer) next() (time.Duration, []byte, error) {
+	for {
+		select {
+		case <-r.closed:
+			return 0, nil, io.EOF
+		default:
+		}
+
+		if !r.lines.Scan() {
+			if err := r.lines.Err(); err != nil {
+				return 0, nil, fmt.Errorf("replay: %w", err)
+			}
+			return 0, nil, io.EOF
+		}
+		r.line++
+
+		line := strings.TrimSpace(r.lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		at, err := time.ParseDuration(fields[0])
+		if err != nil {
+			return 0, nil, fmt.Errorf("replay line %d: invalid time %q", r.line, fields[0])
+		}
+		data, err := hex.DecodeString(strings.Join(fields[1:], ""))
+		if err != nil {
+			return 0, nil, fmt.Errorf("replay line %d: invalid bytes: %w", r.line, err)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		return at, data, nil
+	}
+}
+
+func (r *replayer) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (r *replayer) Close() error {
+	r.closeOnce.Do(func() { close(r.closed) })
+	return nil
+}
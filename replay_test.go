@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptureTimestamps(t *testing.T) {
+	clock := newFakeClock()
+	client, peer := net.Pipe()
+	defer peer.Close()
+
+	received := make(chan []byte, 2)
+	var capture bytes.Buffer
+	sw, err := NewTesmartSwitchWithConn(client, func(frame []byte) { received <- frame },
+		withClock(clock), WithCapture(&capture), WithHealthCheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer.Write([]byte{0xAA, 0xBB, 0x03, 0x11, 0x02, 0x18})
+	<-received
+	clock.Advance(1500 * time.Millisecond)
+	peer.Write([]byte{0xAA, 0xBB, 0x03, 0x11, 0x00, 0x16})
+	<-received
+
+	sw.Close()
+
+	want := "0s AA BB 03 11 02 18\n1.5s AA BB 03 11 00 16\n"
+	if got := capture.String(); got != want {
+		t.Errorf("capture = %q, want %q", got, want)
+	}
+}
+
+func TestReplayRecordedTiming(t *testing.T) {
+	clock := newFakeClock()
+	capture := "# annotated by hand\n0s AA BB 03 11 02 18\n\n2s AA BB 03 11 00 16\n"
+
+	received := make(chan time.Time, 2)
+	sw, err := NewTesmartSwitchFromReplay(strings.NewReader(capture), nil,
+		withClock(clock), WithRecordedTiming(),
+		WithTimedReceiver(func(_ []byte, at time.Time) { received <- at }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	start := clock.Now()
+	if at := <-received; !at.Equal(start) {
+		t.Errorf("first frame read at %v, want %v", at, start)
+	}
+
+	select {
+	case <-received:
+		t.Fatal("second frame replayed before its time")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if d := clock.AdvanceToNext(t); d != 2*time.Second {
+		t.Errorf("replay waited %v for the second frame, want 2s", d)
+	}
+	if at := <-received; at.Sub(start) != 2*time.Second {
+		t.Errorf("second frame read %v after the first, want 2s", at.Sub(start))
+	}
+
+	select {
+	case <-sw.Done():
+	case <-time.After(time.Second):
+		t.Fatal("switch not done at the end of the capture")
+	}
+}
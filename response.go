@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ResponseType classifies a frame received from the switch.
+type ResponseType int
+
+const (
+	// ResponseUnknown is any frame that is not a valid OUTPUT frame.
+	ResponseUnknown ResponseType = iota
+	// ResponseInput reports the active input. The switch sends it in answer
+	// to GET_CURRENT_INPUT and as the acknowledgement of SWITCH_INPUT, so the
+	// two cannot be told apart on the wire.
+	ResponseInput
+)
+
+// String returns the name used for the type in JSON, e.g. "current_input".
+func (t ResponseType) String() string {
+	if t == ResponseInput {
+		return "current_input"
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler using String.
+func (t ResponseType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler; names it does not know
+// become ResponseUnknown.
+func (t *ResponseType) UnmarshalText(text []byte) error {
+	*t = ResponseUnknown
+	if string(text) == ResponseInput.String() {
+		*t = ResponseInput
+	}
+	return nil
+}
+
+// Response is a frame received from the switch.
+type Response struct {
+	Type  ResponseType
+	Input int    // 1-based input number, only set for ResponseInput
+	Raw   []byte // the frame as read from the wire
+
+	// Checksum is the checksum byte of a report and ExpectedChecksum the
+	// one computed from its input byte. Both are zero for frames that are
+	// not shaped like a report.
+	Checksum, ExpectedChecksum byte
+
+	// At is when the read that completed the frame returned, for frames
+	// read by a Switch, and zero for frames parsed with ParseResponse.
+	At time.Time
+}
+
+// ParseResponse parses a frame received from, or captured off the wire of, a
+// switch speaking DefaultProtocol. The error, wrapping ErrInvalidResponse,
+// tells why the frame is not a valid report: a wrong length or header, an
+// opcode other than the report's, or a checksum mismatch, which also wraps
+// ErrChecksum. ExtractInput covers the common case of only needing the input.
+func ParseResponse(frame []byte) (Response, error) {
+	return DefaultProtocol.parseFrame(frame)
+}
+
+// responseJSON is the JSON form of a Response, e.g.
+//
+//	{"type":"current_input","input":3,"raw":"AA BB 03 11 02 18","at":"2024-05-01T12:00:00.5Z"}
+type responseJSON struct {
+	Type  ResponseType `json:"type"`
+	Input int          `json:"input,omitempty"`
+	Raw   string       `json:"raw"`
+	At    *time.Time   `json:"at,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. Raw is rendered as hex, like in the
+// debug log.
+func (r Response) MarshalJSON() ([]byte, error) {
+	v := responseJSON{Type: r.Type, Input: r.Input, Raw: printHex(r.Raw)}
+	if !r.At.IsZero() {
+		v.At = &r.At
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting what MarshalJSON
+// produces.
+func (r *Response) UnmarshalJSON(data []byte) error {
+	var v responseJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	raw, err := hex.DecodeString(strings.ReplaceAll(v.Raw, " ", ""))
+	if err != nil {
+		return fmt.Errorf("response raw: %w", err)
+	}
+
+	*r, _ = ParseResponse(raw)
+	r.Type, r.Input = v.Type, v.Input
+	if v.At != nil {
+		r.At = *v.At
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsValidResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		want  bool
+	}{
+		{"input 1", []byte{0xAA, 0xBB, 0x03, 0x11, 0x00, 0x16}, true},
+		{"input 16", []byte{0xAA, 0xBB, 0x03, 0x11, 0x0F, 0x25}, true},
+		{"bad checksum", []byte{0xAA, 0xBB, 0x03, 0x11, 0x00, 0x17}, false},
+		{"not a report", []byte{0xAA, 0xBB, 0x03, 0x01, 0x02, 0xEE}, false},
+		{"bad header", []byte{0xAA, 0xBC, 0x03, 0x11, 0x00, 0x16}, false},
+		{"short", []byte{0xAA, 0xBB, 0x03}, false},
+		{"long", []byte{0xAA, 0xBB, 0x03, 0x11, 0x00, 0x16, 0x00}, false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsValidResponse(tt.frame); got != tt.want {
+			t.Errorf("%s: IsValidResponse(% X) = %v, want %v", tt.name, tt.frame, got, tt.want)
+		}
+		if _, err := ParseResponse(tt.frame); (err == nil) != tt.want {
+			t.Errorf("%s: ParseResponse(% X) = %v, disagrees with IsValidResponse", tt.name, tt.frame, err)
+		}
+	}
+}
+
+func TestCapturedReports(t *testing.T) {
+	// Reports captured from an 8 port and a 16 port switch.
+	tests := []struct {
+		frame string
+		input int
+	}{
+		{"AA BB 03 11 00 16", 1},
+		{"AA BB 03 11 01 17", 2},
+		{"AA BB 03 11 04 1A", 5},
+		{"AA BB 03 11 07 1D", 8},
+		{"AA BB 03 11 0A 20", 11},
+		{"AA BB 03 11 0F 25", 16},
+	}
+	for _, tt := range tests {
+		frame, err := hex.DecodeString(strings.ReplaceAll(tt.frame, " ", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := ParseResponse(frame)
+		if err != nil {
+			t.Errorf("ParseResponse(%s) = %v", tt.frame, err)
+			continue
+		}
+		if r.Input != tt.input {
+			t.Errorf("ParseResponse(%s) reports input %d, want %d", tt.frame, r.Input, tt.input)
+		}
+		if got := DefaultProtocol.Checksum(frame[4]); got != frame[5] {
+			t.Errorf("checksum of %s = %02X, want the captured %02X", tt.frame, got, frame[5])
+		}
+	}
+}
+
+func TestResponseJSON(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 500_000_000, time.UTC)
+	tests := []struct {
+		name  string
+		frame []byte
+		at    time.Time
+		want  string
+	}{
+		{"input report", []byte{0xAA, 0xBB, 0x03, 0x11, 0x02, 0x18}, at,
+			`{"type":"current_input","input":3,"raw":"AA BB 03 11 02 18","at":"2024-05-01T12:00:00.5Z"}`},
+		{"bad checksum", []byte{0xAA, 0xBB, 0x03, 0x11, 0x02, 0x19}, at,
+			`{"type":"unknown","raw":"AA BB 03 11 02 19","at":"2024-05-01T12:00:00.5Z"}`},
+		{"not a report", []byte{0xAA, 0xBB, 0x03, 0x01, 0x02, 0xEE}, at,
+			`{"type":"unknown","raw":"AA BB 03 01 02 EE","at":"2024-05-01T12:00:00.5Z"}`},
+		{"zero at", []byte{0xAA, 0xBB, 0x03, 0x11, 0x00, 0x16}, time.Time{},
+			`{"type":"current_input","input":1,"raw":"AA BB 03 11 00 16"}`},
+	}
+	for _, tt := range tests {
+		r, _ := ParseResponse(tt.frame)
+		r.At = tt.at
+
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Errorf("%s: Marshal: %v", tt.name, err)
+			continue
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s: Marshal = %s, want %s", tt.name, data, tt.want)
+		}
+
+		var got Response
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Errorf("%s: Unmarshal(%s): %v", tt.name, data, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, r) {
+			t.Errorf("%s: Unmarshal(Marshal(r)) = %+v, want %+v", tt.name, got, r)
+		}
+	}
+}
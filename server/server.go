@@ -0,0 +1,193 @@
+// Package server exposes a TESmart switch over a small JSON HTTP API:
+//
+//	GET  /input              current input, e.g. {"input":3}
+//	POST /input/{n}          switch to input n
+//	POST /buzzer/mute        mute the buzzer
+//	POST /buzzer/unmute      unmute the buzzer
+//	POST /led-timeout/{secs} set the LED timeout, 0 disables it
+//	GET  /ws                 WebSocket status stream, with WithStream
+//	GET  /healthz            connection state, for liveness and readiness probes
+//
+// Errors are returned as {"error":"..."} with a status code that reflects the
+// cause, e.g. 400 for malformed values and 503 while the switch is not
+// connected.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	commands "github.com/mfds/tesmart-commands"
+)
+
+// Server is an http.Handler controlling a single switch.
+type Server struct {
+	sw  commands.Controller
+	mux *http.ServeMux
+	hub *Hub
+}
+
+// Option configures optional behaviour of a Server.
+type Option func(*Server)
+
+// WithStream serves the WebSocket status stream of hub at GET /ws. hub has
+// to receive the switch's responses and connection changes, see Hub.
+func WithStream(hub *Hub) Option {
+	return func(s *Server) {
+		s.hub = hub
+	}
+}
+
+// New returns a Server for sw. The caller keeps ownership of sw.
+func New(sw commands.Controller, opts ...Option) *Server {
+	s := &Server{sw: sw, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mux.HandleFunc("GET /input", s.getInput)
+	s.mux.HandleFunc("POST /input/{n}", s.switchInput)
+	s.mux.HandleFunc("POST /buzzer/mute", s.muteBuzzer)
+	s.mux.HandleFunc("POST /buzzer/unmute", s.unmuteBuzzer)
+	s.mux.HandleFunc("POST /led-timeout/{secs}", s.setLedTimeout)
+	s.mux.HandleFunc("GET /healthz", s.health)
+	if s.hub != nil {
+		s.mux.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+			s.hub.serve(s.sw, w, r)
+		})
+	}
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) getInput(w http.ResponseWriter, r *http.Request) {
+	input, err := s.sw.GetCurrentInput(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"input": input})
+}
+
+func (s *Server) switchInput(w http.ResponseWriter, r *http.Request) {
+	input, ok := pathInt(w, r, "n")
+	if !ok {
+		return
+	}
+
+	if err := s.sw.SwitchInputContext(r.Context(), input); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"input": input})
+}
+
+func (s *Server) muteBuzzer(w http.ResponseWriter, r *http.Request) {
+	if err := s.sw.MuteBuzzerContext(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"buzzer": false})
+}
+
+func (s *Server) unmuteBuzzer(w http.ResponseWriter, r *http.Request) {
+	if err := s.sw.UnmuteBuzzerContext(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]bool{"buzzer": true})
+}
+
+func (s *Server) setLedTimeout(w http.ResponseWriter, r *http.Request) {
+	secs, ok := pathInt(w, r, "secs")
+	if !ok {
+		return
+	}
+
+	if err := s.sw.SetLedTimeoutContext(r.Context(), secs); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"led_timeout": secs})
+}
+
+type healthBody struct {
+	State      string `json:"state"`
+	Input      int    `json:"input,omitempty"`       // last reported, omitted until known
+	RemoteAddr string `json:"remote_addr,omitempty"` // omitted while disconnected
+}
+
+// health replies 200 while the switch is connected and 503 otherwise, e.g.
+// {"state":"connected","input":3,"remote_addr":"192.168.1.10:5000"}. It does
+// not query the switch, so probes cost nothing on the wire.
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	state := s.sw.State()
+	body := healthBody{State: state.String()}
+	if input, ok := s.sw.LastKnownInput(); ok {
+		body.Input = input
+	}
+	if addr := s.sw.RemoteAddr(); addr != nil {
+		body.RemoteAddr = addr.String()
+	}
+
+	status := http.StatusOK
+	if state != commands.Connected {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, body)
+}
+
+// pathInt parses the named path value, replying 400 if it is not a number.
+func pathInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	n, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: name + " must be a number"})
+		return 0, false
+	}
+	return n, true
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// statusFor maps errors returned by the switch to HTTP status codes.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, commands.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, commands.ErrNotConnected):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, commands.ErrUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, commands.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusFor(err), errorBody{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	commands "github.com/mfds/tesmart-commands"
+)
+
+func TestStatusFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{commands.ErrInvalidInput, http.StatusBadRequest},
+		{commands.ErrNotConnected, http.StatusServiceUnavailable},
+		{commands.ErrUnsupported, http.StatusNotImplemented},
+		{commands.ErrRateLimited, http.StatusTooManyRequests},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{context.Canceled, http.StatusServiceUnavailable},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("switch input 3: %w", tt.err)
+		if got := statusFor(err); got != tt.want {
+			t.Errorf("statusFor(%v) = %d, want %d", err, got, tt.want)
+		}
+	}
+}
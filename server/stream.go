@@ -0,0 +1,174 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	commands "github.com/mfds/tesmart-commands"
+)
+
+// A Hub pushes the state of a switch to WebSocket clients. Register its
+// handlers when creating the switch and mount it with WithStream:
+//
+//	hub := server.NewHub()
+//	sw, err := commands.NewTesmartSwitch(host, port, nil,
+//		commands.WithResponseReceiver(hub.HandleResponse),
+//		commands.WithConnectionChangeHandler(hub.HandleConnectionChange))
+//	...
+//	srv := server.New(sw, server.WithStream(hub))
+//
+// Clients connecting to GET /ws first receive the last known state and then
+// every change, as JSON messages:
+//
+//	{"type":"input","input":3}
+//	{"type":"state","state":"connected"}
+//
+// They may switch inputs by sending {"type":"switch","input":3}. Failures are
+// answered with {"type":"error","error":"..."}.
+type Hub struct {
+	mu      sync.Mutex
+	clients map[*streamClient]struct{}
+	input   int    // 0 until the first report
+	state   string // "" until the first transition
+}
+
+// streamBuffer is how many messages are queued for a client before it is
+// considered too slow and disconnected.
+const streamBuffer = 16
+
+type streamClient struct {
+	send chan message
+}
+
+type message struct {
+	Type  string `json:"type"`
+	Input int    `json:"input,omitempty"`
+	State string `json:"state,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// NewHub returns a Hub without clients.
+func NewHub() *Hub {
+	return &Hub{clients: make(map[*streamClient]struct{})}
+}
+
+// HandleResponse forwards the input reported by the switch to the clients if
+// it changed.
+func (h *Hub) HandleResponse(r commands.Response) {
+	if r.Type != commands.ResponseInput {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if r.Input == h.input {
+		return
+	}
+	h.input = r.Input
+	h.broadcastLocked(message{Type: "input", Input: r.Input})
+}
+
+// HandleConnectionChange forwards the connection state of the switch to the
+// clients.
+func (h *Hub) HandleConnectionChange(state commands.ConnectionState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = state.String()
+	h.broadcastLocked(message{Type: "state", State: h.state})
+}
+
+func (h *Hub) broadcastLocked(m message) {
+	for c := range h.clients {
+		select {
+		case c.send <- m:
+		default:
+			// Too slow; dropping it keeps the others up to date.
+			h.removeLocked(c)
+		}
+	}
+}
+
+func (h *Hub) add() *streamClient {
+	c := &streamClient{send: make(chan message, streamBuffer)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state != "" {
+		c.send <- message{Type: "state", State: h.state}
+	}
+	if h.input != 0 {
+		c.send <- message{Type: "input", Input: h.input}
+	}
+	h.clients[c] = struct{}{}
+	return c
+}
+
+func (h *Hub) remove(c *streamClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(c)
+}
+
+func (h *Hub) removeLocked(c *streamClient) {
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+var upgrader = websocket.Upgrader{}
+
+// serve upgrades the request and streams to the client until either side
+// closes the connection. Commands from the client are run against sw.
+func (h *Hub) serve(sw commands.Controller, w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has replied to the client already.
+		return
+	}
+	defer ws.Close()
+
+	c := h.add()
+	defer h.remove(c)
+
+	// Replies to commands share the queue with broadcasts, so that only the
+	// writer goroutine writes to ws.
+	reply := func(m message) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.clients[c]; !ok {
+			return
+		}
+		select {
+		case c.send <- m:
+		default:
+			h.removeLocked(c)
+		}
+	}
+
+	go func() {
+		defer ws.Close()
+		for m := range c.send {
+			if err := ws.WriteJSON(m); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		var m message
+		if err := ws.ReadJSON(&m); err != nil {
+			return
+		}
+
+		switch m.Type {
+		case "switch":
+			if err := sw.SwitchInputContext(r.Context(), m.Input); err != nil {
+				reply(message{Type: "error", Error: err.Error()})
+			}
+		default:
+			reply(message{Type: "error", Error: "unknown message type " + m.Type})
+		}
+	}
+}
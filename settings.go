@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+)
+
+// settings holds the values last sent for the settings the switch cannot
+// report back. It is guarded by Switch.mu.
+type settings struct {
+	buzzerKnown     bool
+	buzzer          bool
+	ledTimeoutKnown bool
+	ledTimeout      int
+	autoDetectKnown bool
+	autoDetect      bool
+}
+
+// remember records the setting carried by command after it has been sent.
+func (t *Switch) remember(command []byte) {
+	if len(command) < 5 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch command[3] {
+	case MUTE_BUZZER[3]:
+		t.settings.buzzerKnown = true
+		t.settings.buzzer = command[4] == UNMUTE_BUZZER[4]
+	case SET_LED_TIMEOUT[3]:
+		t.settings.ledTimeoutKnown = true
+		t.settings.ledTimeout = int(command[4])
+	case ENABLE_AUTO_INPUT_DETECTION[3]:
+		t.settings.autoDetectKnown = true
+		t.settings.autoDetect = command[4] == ENABLE_AUTO_INPUT_DETECTION[4]
+	}
+}
+
+// GetBuzzerState reports whether the buzzer is enabled.
+//
+// The switch has no command to query the buzzer, so this returns the state
+// last set through this Switch, or ErrStateUnknown if it has not been set
+// since the Switch was created. Changes made on the device itself or by
+// other clients are not seen.
+func (t *Switch) GetBuzzerState(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.settings.buzzerKnown {
+		return false, fmt.Errorf("buzzer: %w", ErrStateUnknown)
+	}
+	return t.settings.buzzer, nil
+}
+
+// GetLedTimeout returns the LED timeout in seconds, 0 meaning disabled.
+//
+// Like GetBuzzerState, it returns the value last set through this Switch, or
+// ErrStateUnknown, because the switch cannot report it.
+func (t *Switch) GetLedTimeout(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.settings.ledTimeoutKnown {
+		return 0, fmt.Errorf("LED timeout: %w", ErrStateUnknown)
+	}
+	return t.settings.ledTimeout, nil
+}
+
+// SetLedTimeoutAndVerify is meant to set the LED timeout and confirm that the
+// switch applied it. The switch cannot report its LED timeout, so there is
+// nothing to compare against: it behaves exactly like SetLedTimeoutContext,
+// and a switch that ignores the command goes unnoticed.
+func (t *Switch) SetLedTimeoutAndVerify(ctx context.Context, secs int) error {
+	return t.SetLedTimeoutContext(ctx, secs)
+}
@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithSlogLogger sends structured events to l: connects and disconnects,
+// every command sent and every frame received. The events carry the host of
+// the switch, if it was dialed, and, where it applies, the command name and
+// the number of bytes. It is independent of WithLogger.
+func WithSlogLogger(l *slog.Logger) Option {
+	return func(t *Switch) {
+		t.slog = l
+	}
+}
+
+func (t *Switch) logEvent(level slog.Level, msg string, args ...any) {
+	if t.slog == nil {
+		return
+	}
+
+	if t.host != "" {
+		args = append([]any{slog.String("host", t.host)}, args...)
+	}
+	t.slog.Log(context.Background(), level, msg, args...)
+}
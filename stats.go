@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats counts the writes of a Switch since it was created, for quick
+// diagnostics without a Metrics implementation. Health check probes are not
+// counted.
+type Stats struct {
+	CommandsSent uint64    // commands written in full
+	BytesWritten uint64    // bytes written, including those of torn frames
+	SendErrors   uint64    // commands that could not be written
+	LastSent     time.Time // when the last command was written, zero if none was
+}
+
+// stats is updated as commands are sent; the fields are atomic so that Stats
+// does not wait for a write in progress.
+type stats struct {
+	commandsSent atomic.Uint64
+	bytesWritten atomic.Uint64
+	sendErrors   atomic.Uint64
+	lastSent     atomic.Int64 // unix nanoseconds, 0 if nothing was sent
+}
+
+// Stats returns the write counters of the switch. The counters are read one
+// by one, so they may be off by one command while another goroutine sends.
+func (t *Switch) Stats() Stats {
+	s := Stats{
+		CommandsSent: t.stats.commandsSent.Load(),
+		BytesWritten: t.stats.bytesWritten.Load(),
+		SendErrors:   t.stats.sendErrors.Load(),
+	}
+	if last := t.stats.lastSent.Load(); last != 0 {
+		s.LastSent = time.Unix(0, last)
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics is a Metrics that keeps the names of the commands it is
+// told about.
+type recordingMetrics struct {
+	mu     sync.Mutex
+	sent   []string
+	failed []string
+}
+
+func (m *recordingMetrics) CommandSent(command string) {
+	m.mu.Lock()
+	m.sent = append(m.sent, command)
+	m.mu.Unlock()
+}
+
+func (m *recordingMetrics) SendFailed(command string) {
+	m.mu.Lock()
+	m.failed = append(m.failed, command)
+	m.mu.Unlock()
+}
+
+func (m *recordingMetrics) Reconnected()      {}
+func (m *recordingMetrics) InputReported(int) {}
+
+func (m *recordingMetrics) commands() (sent, failed []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.sent...), append([]string(nil), m.failed...)
+}
+
+// recordingTracer is a Tracer that keeps the commands it is asked to trace.
+type recordingTracer struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (tr *recordingTracer) StartCommand(ctx context.Context, info CommandInfo) (context.Context, func(error)) {
+	tr.mu.Lock()
+	tr.commands = append(tr.commands, info.Command)
+	tr.mu.Unlock()
+	return ctx, endNothing
+}
+
+func (tr *recordingTracer) traced() []string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append([]string(nil), tr.commands...)
+}
+
+func TestStats(t *testing.T) {
+	clock := newFakeClock()
+	metrics := &recordingMetrics{}
+	tracer := &recordingTracer{}
+	sw, peer := pipeSwitch(t, nil, withClock(clock), WithMetrics(metrics), WithTracer(tracer),
+		WithHealthCheck(true), WithHealthCheckInterval(5*time.Second))
+	writes := readWrites(peer)
+
+	if got := sw.Stats(); got != (Stats{}) {
+		t.Errorf("Stats() of a new switch = %+v, want zero", got)
+	}
+
+	clock.Advance(time.Second)
+	if err := sw.SwitchInput(3); err != nil {
+		t.Fatal(err)
+	}
+	next(t, writes)
+	if err := sw.MuteBuzzer(); err != nil {
+		t.Fatal(err)
+	}
+	next(t, writes)
+
+	want := Stats{CommandsSent: 2, BytesWritten: 12, LastSent: clock.Now()}
+	if got := sw.Stats(); !sameStats(got, want) {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	// A probe leaves the user-facing counters alone.
+	clock.AdvanceToNext(t)
+	if got := next(t, writes); !bytes.Equal(got, GET_CURRENT_INPUT) {
+		t.Fatalf("wrote % X, want the probe", got)
+	}
+	if got := sw.Stats(); !sameStats(got, want) {
+		t.Errorf("Stats() after a probe = %+v, want %+v", got, want)
+	}
+
+	peer.Close()
+	if err := sw.SwitchInput(4); err == nil {
+		t.Fatal("SwitchInput to a closed peer succeeded")
+	}
+	if got := sw.Stats(); got.SendErrors != 1 || got.CommandsSent != 2 {
+		t.Errorf("Stats() after a failed send = %+v, want 1 error and 2 commands", got)
+	}
+
+	sent, failed := metrics.commands()
+	if want := []string{"switch_input", "mute_buzzer"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("metrics saw %q sent, want %q", sent, want)
+	}
+	if want := []string{"switch_input"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("metrics saw %q failed, want %q", failed, want)
+	}
+	if got, want := tracer.traced(), []string{"switch_input", "mute_buzzer", "switch_input"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("traced %q, want %q", got, want)
+	}
+}
+
+// sameStats compares Stats, ignoring the location of LastSent.
+func sameStats(a, b Stats) bool {
+	return a.LastSent.Equal(b.LastSent) && a.CommandsSent == b.CommandsSent &&
+		a.BytesWritten == b.BytesWritten && a.SendErrors == b.SendErrors
+}
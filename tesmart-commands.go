@@ -2,21 +2,24 @@ package commands
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"log/slog"
 	"net"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
-	"syscall"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	SWITCH_INPUT                 = []byte{0xAA, 0xBB, 0x03, 0x01, 0x00, 0xEE} // 5th byte is input (1-16 or 1-8)
+	SWITCH_INPUT                 = []byte{0xAA, 0xBB, 0x03, 0x01, 0x00, 0xEE} // 5th byte is input (1 to Model.Inputs())
 	SET_LED_TIMEOUT              = []byte{0xAA, 0xBB, 0x03, 0x03, 0x00, 0xEE} // 5th byte is input (in secs. 0x00 to 0x1E. 0 will disable timeout)
 	MUTE_BUZZER                  = []byte{0xAA, 0xBB, 0x03, 0x02, 0x00, 0xEE}
 	UNMUTE_BUZZER                = []byte{0xAA, 0xBB, 0x03, 0x02, 0x01, 0xEE}
@@ -24,210 +27,503 @@ var (
 	DISABLE_AUTO_INPUT_DETECTION = []byte{0xAA, 0xBB, 0x03, 0x81, 0x00, 0xEE} // Only on the 8 port model
 	GET_CURRENT_INPUT            = []byte{0xAA, 0xBB, 0x03, 0x10, 0x00, 0xEE}
 
-	OUTPUT = []byte{0xAA, 0xBB, 0x03, 0x11} // last two bytes are: input and input+0x16 (checksum probably)
+	OUTPUT = []byte{0xAA, 0xBB, 0x03, 0x11} // last two bytes are: input and its checksum (see responseChecksumOffset)
 )
 
+// commandName returns a short name for a command frame, for logs and metrics.
+func commandName(command []byte) string {
+	if len(command) < 5 {
+		return "unknown"
+	}
+
+	switch command[3] {
+	case SWITCH_INPUT[3]:
+		return "switch_input"
+	case SET_LED_TIMEOUT[3]:
+		return "set_led_timeout"
+	case MUTE_BUZZER[3]:
+		if command[4] == MUTE_BUZZER[4] {
+			return "mute_buzzer"
+		}
+		return "unmute_buzzer"
+	case ENABLE_AUTO_INPUT_DETECTION[3]:
+		if command[4] == ENABLE_AUTO_INPUT_DETECTION[4] {
+			return "enable_auto_input_detection"
+		}
+		return "disable_auto_input_detection"
+	case GET_CURRENT_INPUT[3]:
+		return "get_current_input"
+	}
+	return "unknown"
+}
+
+// responseChecksumOffset is the fixed value the switch adds to the input byte
+// to produce the trailing checksum byte of an OUTPUT frame, truncated to a
+// byte: the switch reports input 1 as AA BB 03 11 00 16. A plain sum of the
+// first five bytes (0x79 + input) does not match what the device sends, so
+// the observed rule is used, see DefaultProtocol. Commands sent to the switch
+// carry no checksum: their last byte is the fixed 0xEE terminator.
+const responseChecksumOffset = 0x16
+
+// Debug is the logger used by switches created without WithLogger, unless
+// WithDebugFromEnv applies. It discards everything by default.
 var Debug = log.New(ioutil.Discard, "DEBUG: ", 0)
 
-type tesmartSwitch struct {
-	host          string
-	conn          net.Conn
+// Switch is a connection to a TESmart KVM switch. Its methods are safe for
+// concurrent use; every command frame is written to the wire atomically.
+//
+// A Switch is created connected by one of the constructors, which start a
+// goroutine reading from the switch and, unless disabled, one running the
+// health check. It is then used until Close, which is also how it satisfies
+// io.Closer. Close stops reconnecting, closes the connection and waits for
+// every goroutine the Switch started, including pending handler calls, so
+// none is left once it returns. Commands issued after Close fail with
+// ErrNotConnected. Handlers must not call Close themselves, as Close waits
+// for them.
+type Switch struct {
+	host         string
+	port         string
+	dialer       Dialer
+	tlsConfig    *tls.Config // nil for plain TCP
+	keepAlive    time.Duration
+	open         func(ctx context.Context) (io.ReadWriteCloser, error) // nil if the transport cannot be reopened
+	receiverFunc func([]byte)
+	responseFunc func(Response)
+	rawFunc      func([]byte)
+	timedFunc    func([]byte, time.Time)
+	wireTap      func(Direction, []byte)
+	tapNotifier  notifier
+	logger       *log.Logger
+	debugFromEnv bool
+	slog         *slog.Logger
+	clock        clock
+	metrics      Metrics
+	tracer       Tracer
+	model        Model
+	labels       map[int]string // see WithLabels
+
+	dialTimeout         time.Duration
+	writeTimeout        time.Duration
+	readDeadline        time.Duration
+	queryWindow         time.Duration
+	protocol            Protocol
+	noHealthCheck       bool
+	healthCheckInterval time.Duration
+	disconnectHandler   func(error)
+
+	connectionChangeHandler func(ConnectionState)
+	notifier                notifier
+
+	debounce     time.Duration
+	debounced    chan int
+	debounceStop chan struct{} // closed by Close to flush
+	debounceDone chan struct{} // closed when the debounce loop exits
+
+	skipRedundant bool
+
+	ledTimeoutMax int // see WithMaxLedTimeout, 0 for the model's
+
+	dryRun   bool
+	recorder recorder
+
+	capture        *capture // nil without WithCapture
+	recordedTiming bool
+
+	reconnect         bool
+	reconnectBackoff  time.Duration
+	reconnectMax      time.Duration
+	reconnectJitter   float64
+	reconnectAttempts int
+	reconnectHandler  func(attempt int, err error)
+
+	retries    int
+	retryDelay time.Duration
+	limiter    *limiter // nil without WithRateLimit
+
+	ctx    context.Context // cancelled by Close
+	cancel context.CancelFunc
+
+	done     chan struct{} // closed once the connection is lost for good
+	doneOnce sync.Once
+
+	writeLock chan struct{} // held while writing so frames never interleave, see lockWrite
+
+	mu            sync.Mutex
+	conn          io.ReadWriteCloser
 	connectionCtx context.Context
 	cancelFunc    context.CancelFunc
-	receiverFunc  func([]byte)
+	state         ConnectionState
+	stateWatchers []func(ConnectionState)
+	lastRead      int64 // unix nanoseconds, accessed atomically
+	settings      settings
+	stats         stats
+
+	lastInput  atomic.Int64 // last reported input, 0 until the first report
+	inputFresh atomic.Bool  // lastInput was reported on the current connection
+
+	subMu       sync.Mutex
+	subscribers map[chan Response]struct{}
+	reports     chan int
+
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+var _ io.Closer = (*Switch)(nil)
+
+// NewTesmartSwitch connects to the switch at host:port. host may be a name, an
+// IPv4 address or an IPv6 address, with or without brackets. receiverFunc is
+// called with every valid report read from the switch, complete and with a
+// correct checksum; other frames are logged and dropped. It may be nil, in
+// which case incoming frames are discarded. Use WithRawReceiver to see
+// everything read, and WithResponseReceiver to see every frame parsed.
+func NewTesmartSwitch(host string, port string, receiverFunc func([]byte), opts ...Option) (*Switch, error) {
+	return NewTesmartSwitchContext(context.Background(), host, port, receiverFunc, opts...)
 }
 
-func NewTesmartSwitch(host string, port string, receiverFunc func([]byte)) (*tesmartSwitch, error) {
-	t := tesmartSwitch{}
+// NewTesmartSwitchContext is like NewTesmartSwitch but gives up dialing when
+// ctx is done. Once connected, ctx has no further effect on the switch.
+func NewTesmartSwitchContext(ctx context.Context, host string, port string, receiverFunc func([]byte), opts ...Option) (*Switch, error) {
+	// Accept IPv6 literals in their URL form too, e.g. "[fe80::1]".
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
 
-	if _, ok := os.LookupEnv("DEBUG"); ok {
-		Debug.SetOutput(os.Stdout)
+	if err := validateAddress(host, port); err != nil {
+		return nil, err
 	}
 
-	err := t.connect(host, port)
+	t := newSwitch(receiverFunc, opts)
+	t.host = host
+	t.port = port
+	t.open = t.dialTCP
+
+	err := t.connect(ctx)
 	if err != nil {
+		t.cancel()
 		return nil, err
 	}
 
-	t.receiverFunc = receiverFunc
+	return t, nil
+}
 
-	return &t, nil
+// NewTesmartSwitchWithConn wraps an already established connection, e.g. one
+// end of a net.Pipe in tests. The switch takes ownership of conn and closes it
+// on Close. Such a switch has no address to dial and is never reconnected.
+func NewTesmartSwitchWithConn(conn net.Conn, receiverFunc func([]byte), opts ...Option) (*Switch, error) {
+	return NewTesmartSwitchWithTransport(conn, receiverFunc, opts...)
 }
 
-func (t *tesmartSwitch) SwitchInput(input int) error {
-	if input < 1 && input > 16 {
-		return errors.New("invalid input value")
+// NewTesmartSwitchWithTransport talks to the switch over an arbitrary byte
+// stream, e.g. a serial port as opened by package tesmartserial. If the
+// transport has a SetReadDeadline method it is used to poll for cancellation;
+// otherwise reads block until data arrives or the transport is closed. Like
+// NewTesmartSwitchWithConn, the switch owns rw and never reconnects it.
+func NewTesmartSwitchWithTransport(rw io.ReadWriteCloser, receiverFunc func([]byte), opts ...Option) (*Switch, error) {
+	t := newSwitch(receiverFunc, opts)
+
+	err := t.attach(rw)
+	if err != nil {
+		t.cancel()
+		return nil, err
 	}
 
-	command := injectInputToPayload(SWITCH_INPUT, byte(input))
-	return t.send(command)
+	return t, nil
 }
 
-func (t *tesmartSwitch) SetLedTimeout(input int) error {
-	if input < 0 && input > 30 {
-		return errors.New("invalid LED timeout value")
+// validateAddress rejects host and port values that cannot form a dial
+// address, so callers get a clear error instead of a confusing dial failure.
+// IPv6 literals such as "::1" are accepted without brackets.
+func validateAddress(host, port string) error {
+	if host == "" {
+		return errors.New("invalid host: must not be empty")
 	}
 
-	command := injectInputToPayload(GET_CURRENT_INPUT, byte(input))
-	return t.send(command)
-}
+	if strings.Contains(host, ":") {
+		literal, _, _ := strings.Cut(host, "%") // drop an IPv6 zone
+		if net.ParseIP(literal) == nil {
+			return fmt.Errorf("invalid host %q: must not include a port", host)
+		}
+	}
 
-func (t *tesmartSwitch) MuteBuzzer() error {
-	return t.send(MUTE_BUZZER)
-}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q: must be a number between 1 and 65535", port)
+	}
 
-func (t *tesmartSwitch) UnmuteBuzzer() error {
-	return t.send(UNMUTE_BUZZER)
+	return nil
 }
 
-func (t *tesmartSwitch) EnableAutoInputDetection() error {
-	return t.send(ENABLE_AUTO_INPUT_DETECTION)
-}
+func newSwitch(receiverFunc func([]byte), opts []Option) *Switch {
+	t := &Switch{
+		dialer:              &net.Dialer{},
+		clock:               realClock{},
+		metrics:             noMetrics{},
+		tracer:              noTracer{},
+		reports:             make(chan int, reportsBuffer),
+		dialTimeout:         DefaultDialTimeout,
+		writeTimeout:        DefaultWriteTimeout,
+		readDeadline:        DefaultReadDeadline,
+		protocol:            DefaultProtocol,
+		healthCheckInterval: DefaultHealthCheckInterval,
+		reconnectBackoff:    DefaultReconnectBackoff,
+		reconnectMax:        DefaultMaxReconnectBackoff,
+		retryDelay:          DefaultRetryDelay,
+		receiverFunc:        receiverFunc,
+		writeLock:           make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.limiter != nil {
+		t.limiter.clock = t.clock
+	}
+
+	if t.logger == nil {
+		t.logger = Debug
+		if _, ok := os.LookupEnv("DEBUG"); ok && t.debugFromEnv {
+			t.logger = log.New(os.Stdout, "DEBUG: ", 0)
+		}
+	}
+
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	t.done = make(chan struct{})
+
+	if t.debounce > 0 {
+		t.debounced = make(chan int)
+		t.debounceStop = make(chan struct{})
+		t.debounceDone = make(chan struct{})
+		t.wg.Add(1)
+		go t.debounceLoop()
+	}
 
-func (t *tesmartSwitch) DisableAutoInputDetection() error {
-	return t.send(DISABLE_AUTO_INPUT_DETECTION)
+	return t
 }
 
-func (t *tesmartSwitch) SendGetCurrentInput() error {
-	return t.send(GET_CURRENT_INPUT)
+func (t *Switch) SwitchInput(input int) error {
+	return t.SwitchInputContext(context.Background(), input)
 }
 
-func (t *tesmartSwitch) connect(host string, port string) error {
-	Debug.Print("Connecting...")
-	var d net.Dialer
+// SwitchInputContext is like SwitchInput but aborts the write when ctx is done.
+func (t *Switch) SwitchInputContext(ctx context.Context, input int) error {
+	if err := t.checkInput(input); err != nil {
+		return err
+	}
+	if t.autoDetectOn() {
+		t.logger.Printf("Switching to input %d with auto input detection on; the switch may switch back, see SwitchInputForce", input)
+	}
+
+	if t.debounce > 0 {
+		if err := t.queueSwitch(ctx, input); err != nil {
+			return fmt.Errorf("switch input %d: %w", input, err)
+		}
+		return nil
+	}
+	if t.redundantSwitch(input) {
+		return nil
+	}
 
-	dialCtx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	conn, err := d.DialContext(dialCtx, "tcp", host+":"+port)
+	command, err := BuildSwitchInput(input)
 	if err != nil {
-		Debug.Printf("Failed to dial: %v", err)
 		return err
 	}
+	if err := t.send(ctx, command); err != nil {
+		return fmt.Errorf("switch input %d: %w", input, err)
+	}
+	return nil
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// redundantSwitch reports whether switching to input can be skipped, see
+// WithSkipRedundantSwitch.
+func (t *Switch) redundantSwitch(input int) bool {
+	if !t.skipRedundant || !t.inputFresh.Load() || int(t.lastInput.Load()) != input {
+		return false
+	}
+	t.logger.Printf("Already on input %d, not switching", input)
+	return true
+}
 
-	t.host = host
-	t.conn = conn
-	t.connectionCtx = ctx
-	t.cancelFunc = cancel
+// checkInput checks input against the number of inputs of the model, so that
+// e.g. an 8-port switch rejects inputs 9-16.
+func (t *Switch) checkInput(input int) error {
+	if input >= 1 && input <= t.model.Inputs() {
+		return nil
+	}
+	if t.model == ModelUnknown {
+		return fmt.Errorf("switch input %d: %w (must be 1-%d)", input, ErrInvalidInput, t.model.Inputs())
+	}
+	return fmt.Errorf("switch input %d: %w (must be 1-%d on the %v model)", input, ErrInvalidInput, t.model.Inputs(), t.model)
+}
 
-	go t.receiveLoop()
-	go t.checkConnectionLoop()
+// SetLedTimeout sets how many seconds the LEDs stay lit after the input
+// changes, up to MaxLedTimeout. 0 disables the timeout, so that the
+// LEDs are always on; DisableLedTimeout says so by name.
+func (t *Switch) SetLedTimeout(input int) error {
+	return t.SetLedTimeoutContext(context.Background(), input)
+}
 
-	Debug.Printf("Connected to: %s", host+":"+port)
+// SetLedTimeoutContext is like SetLedTimeout but aborts the write when ctx is
+// done.
+func (t *Switch) SetLedTimeoutContext(ctx context.Context, input int) error {
+	if err := t.checkLedTimeout(input); err != nil {
+		return err
+	}
 
+	command, err := buildSetLedTimeout(input, t.MaxLedTimeout())
+	if err != nil {
+		return err
+	}
+	if err := t.send(ctx, command); err != nil {
+		return fmt.Errorf("set LED timeout %d: %w", input, err)
+	}
 	return nil
 }
 
-func (t *tesmartSwitch) send(command []byte) error {
-	Debug.Printf("Sending: %s", printHex(command))
+// DisableLedTimeout keeps the LEDs always on. It sends the same command as
+// SetLedTimeoutContext(ctx, 0).
+func (t *Switch) DisableLedTimeout(ctx context.Context) error {
+	return t.SetLedTimeoutContext(ctx, 0)
+}
 
-	bytesSent, err := t.conn.Write(command)
-	if err != nil {
-		Debug.Printf("Failed to send command: %v", err)
-		return err
+// MaxLedTimeout returns the longest LED timeout, in seconds, SetLedTimeout
+// accepts: the one set with WithMaxLedTimeout, or else that of the model.
+func (t *Switch) MaxLedTimeout() int {
+	if t.ledTimeoutMax != 0 {
+		return t.ledTimeoutMax
 	}
+	return t.model.MaxLedTimeout()
+}
 
-	if bytesSent != 6 {
-		err := fmt.Errorf("wrong amount of byte sent: %d. Expected 6", bytesSent)
-		Debug.Printf(err.Error())
-		return err
+// checkLedTimeout checks secs against MaxLedTimeout.
+func (t *Switch) checkLedTimeout(secs int) error {
+	max := t.MaxLedTimeout()
+	switch {
+	case secs >= 0 && secs <= max:
+		return nil
+	case t.model == ModelUnknown || t.ledTimeoutMax != 0:
+		return fmt.Errorf("set LED timeout %d: %w (must be 0-%d)", secs, ErrInvalidInput, max)
 	}
-	Debug.Printf("Sent: %d", bytesSent)
+	return fmt.Errorf("set LED timeout %d: %w (must be 0-%d on the %v model)", secs, ErrInvalidInput, max, t.model)
+}
+
+func (t *Switch) MuteBuzzer() error {
+	return t.MuteBuzzerContext(context.Background())
+}
 
+// MuteBuzzerContext is like MuteBuzzer but aborts the write when ctx is done.
+func (t *Switch) MuteBuzzerContext(ctx context.Context) error {
+	if err := t.send(ctx, BuildMuteBuzzer()); err != nil {
+		return fmt.Errorf("mute buzzer: %w", err)
+	}
 	return nil
 }
 
-func (t *tesmartSwitch) checkConnectionLoop() {
-	defer func() {
-		t.cancelFunc()
-		t.conn.Close()
-	}()
+func (t *Switch) UnmuteBuzzer() error {
+	return t.UnmuteBuzzerContext(context.Background())
+}
 
-	for {
-		cmd := exec.Command("ping", "-c4", t.host)
+// UnmuteBuzzerContext is like UnmuteBuzzer but aborts the write when ctx is
+// done.
+func (t *Switch) UnmuteBuzzerContext(ctx context.Context) error {
+	if err := t.send(ctx, BuildUnmuteBuzzer()); err != nil {
+		return fmt.Errorf("unmute buzzer: %w", err)
+	}
+	return nil
+}
 
-		if err := cmd.Start(); err != nil {
-			log.Fatalf("cmd.Start: %v", err)
-		}
+// SetBuzzer unmutes the buzzer when enabled is true and mutes it otherwise.
+func (t *Switch) SetBuzzer(enabled bool) error {
+	return t.SetBuzzerContext(context.Background(), enabled)
+}
 
-		if err := cmd.Wait(); err != nil {
-			if exiterr, ok := err.(*exec.ExitError); ok {
-				if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
-					if status.ExitStatus() != 0 {
-						Debug.Printf("Disconnected")
-						return
-					}
-				}
-			} else {
-				log.Fatalf("cmd.Wait: %v", err)
-			}
-		}
-		Debug.Println("PING")
+// SetBuzzerContext is like SetBuzzer but aborts the write when ctx is done.
+func (t *Switch) SetBuzzerContext(ctx context.Context, enabled bool) error {
+	if enabled {
+		return t.UnmuteBuzzerContext(ctx)
 	}
+	return t.MuteBuzzerContext(ctx)
 }
 
-func (t *tesmartSwitch) receiveLoop() {
-	defer t.conn.Close()
+func (t *Switch) EnableAutoInputDetection() error {
+	return t.EnableAutoInputDetectionContext(context.Background())
+}
 
-ReadLoop:
-	for {
-		select {
-		case <-t.connectionCtx.Done():
-			return
-		default:
-			response := make([]byte, 6)
-			t.conn.SetDeadline(time.Now().Add(200 * time.Millisecond))
-			read, err := t.conn.Read(response)
+// EnableAutoInputDetectionContext is like EnableAutoInputDetection but aborts
+// the write when ctx is done.
+func (t *Switch) EnableAutoInputDetectionContext(ctx context.Context) error {
+	if err := t.checkAutoInputDetection(); err != nil {
+		return err
+	}
 
-			if err != nil {
-				if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
-					continue ReadLoop
-				} else if err != io.EOF {
-					Debug.Printf("Failed to read data from socket: %v", err)
-					return
-				}
-			}
+	if err := t.send(ctx, BuildEnableAutoInputDetection()); err != nil {
+		return fmt.Errorf("enable auto input detection: %w", err)
+	}
+	return nil
+}
 
-			if read == 0 {
-				return
-			}
+func (t *Switch) DisableAutoInputDetection() error {
+	return t.DisableAutoInputDetectionContext(context.Background())
+}
 
-			Debug.Printf("Read %d bytes: %s", read, printHex(response))
+// DisableAutoInputDetectionContext is like DisableAutoInputDetection but
+// aborts the write when ctx is done.
+func (t *Switch) DisableAutoInputDetectionContext(ctx context.Context) error {
+	if err := t.checkAutoInputDetection(); err != nil {
+		return err
+	}
 
-			t.receiverFunc(response)
-		}
+	if err := t.send(ctx, BuildDisableAutoInputDetection()); err != nil {
+		return fmt.Errorf("disable auto input detection: %w", err)
 	}
+	return nil
+}
+
+func (t *Switch) checkAutoInputDetection() error {
+	if !t.model.SupportsAutoInputDetection() {
+		return fmt.Errorf("auto input detection on the %v model: %w", t.model, ErrUnsupported)
+	}
+	return nil
 }
 
-func injectInputToPayload(payload []byte, input byte) []byte {
-	command := make([]byte, 6)
-	copy(command, payload)
-	command[4] = input
-	return command
+func (t *Switch) SendGetCurrentInput() error {
+	return t.SendGetCurrentInputContext(context.Background())
 }
 
-func ExtractInput(response []byte) (int, error) {
-	if isValidOutput(response) {
-		return int(response[4]) + 1, nil // input is zero based
+// SendGetCurrentInputContext is like SendGetCurrentInput but aborts the write
+// when ctx is done.
+func (t *Switch) SendGetCurrentInputContext(ctx context.Context) error {
+	if err := t.send(ctx, BuildGetCurrentInput()); err != nil {
+		return fmt.Errorf("get current input: %w", err)
 	}
-	return 0, errors.New("invalid response")
+	return nil
 }
 
-func isValidOutput(output []byte) bool {
-	Debug.Printf("isValidOutput = %s", printHex(output))
+// ExtractInput returns the 1-based input reported by an OUTPUT frame. See
+// ParseResponse for why a frame may be rejected. The length of response is
+// checked before any byte is read, so a short or nil slice, e.g. one that was
+// not checked with IsValidResponse, fails with ErrInvalidResponse rather than
+// panicking.
+func ExtractInput(response []byte) (int, error) {
+	r, err := ParseResponse(response)
+	if err != nil {
+		return 0, err
+	}
+	return r.Input, nil
+}
 
-	return len(output) == 6 &&
-		output[0] == 0xAA &&
-		output[1] == 0xBB &&
-		output[2] == 0x03 &&
-		output[3] == 0x11 &&
-		output[5]-output[4] == 0x16 // checksum?
+// IsValidResponse reports whether output is a well-formed OUTPUT frame, i.e. a
+// current-input report with a correct checksum. It can be used on frames
+// captured outside this package, e.g. from a serial log.
+func IsValidResponse(output []byte) bool {
+	_, err := ParseResponse(output)
+	return err == nil
 }
 
-func printHex(data []byte) (out string) {
-	out = "\033[1D"
-	for _, b := range data {
-		out += strings.ToUpper(fmt.Sprintf("%3.2x", b))
-	}
-	return
+// printHex formats data as space-separated uppercase hex bytes, e.g.
+// "AA BB 03 01 00 EE".
+func printHex(data []byte) string {
+	return fmt.Sprintf("% X", data)
 }
@@ -0,0 +1,350 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipeSwitch returns a switch talking to the other end of a net.Pipe, which
+// the test plays the device on. The health check is off unless opts turn it
+// back on.
+func pipeSwitch(t *testing.T, receiverFunc func([]byte), opts ...Option) (*Switch, net.Conn) {
+	t.Helper()
+
+	client, peer := net.Pipe()
+	opts = append([]Option{WithHealthCheck(false)}, opts...)
+	sw, err := NewTesmartSwitchWithConn(client, receiverFunc, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		peer.Close()
+		sw.Close()
+	})
+	return sw, peer
+}
+
+// readWrites returns a channel receiving everything written to peer, one
+// read at a time, until peer is closed.
+func readWrites(peer net.Conn) <-chan []byte {
+	writes := make(chan []byte, 64)
+	go func() {
+		defer close(writes)
+		buf := make([]byte, 64)
+		for {
+			n, err := peer.Read(buf)
+			if err != nil {
+				return
+			}
+			writes <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+	return writes
+}
+
+// next returns the next value from ch, failing the test if none arrives
+// within a second.
+func next[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+		panic("unreachable")
+	}
+}
+
+// chunkConn is a net.Conn that accepts at most size bytes per Write, like a
+// stream with little room in its send buffer. It yields after every Write so
+// that concurrent writers get to run in between.
+type chunkConn struct {
+	net.Conn
+	size int
+}
+
+func (c chunkConn) Write(b []byte) (int, error) {
+	defer runtime.Gosched()
+	return c.Conn.Write(b[:min(len(b), c.size)])
+}
+
+// dryRunSwitch returns a switch created with WithDryRun, see SentCommands.
+func dryRunSwitch(t *testing.T, opts ...Option) *Switch {
+	t.Helper()
+
+	sw, err := NewTesmartSwitch("192.0.2.1", "5000", nil, append([]Option{WithDryRun()}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sw.Close() })
+	return sw
+}
+
+func TestSwitchInputRange(t *testing.T) {
+	tests := []struct {
+		input int
+		err   string
+	}{
+		{-5, "switch input -5: invalid input (must be 1-16)"},
+		{0, "switch input 0: invalid input (must be 1-16)"},
+		{1, ""},
+		{8, ""},
+		{16, ""},
+		{17, "switch input 17: invalid input (must be 1-16)"},
+		{99, "switch input 99: invalid input (must be 1-16)"},
+	}
+	for _, tt := range tests {
+		sw, peer := pipeSwitch(t, nil)
+		writes := readWrites(peer)
+
+		err := sw.SwitchInput(tt.input)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err || !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("SwitchInput(%d) = %v, want %q", tt.input, err, tt.err)
+			}
+			select {
+			case got := <-writes:
+				t.Errorf("SwitchInput(%d) sent % X, want nothing", tt.input, got)
+			default:
+			}
+			continue
+		}
+
+		want := []byte{0xAA, 0xBB, 0x03, 0x01, byte(tt.input), 0xEE}
+		if err != nil {
+			t.Errorf("SwitchInput(%d) = %v", tt.input, err)
+		} else if got := next(t, writes); !bytes.Equal(got, want) {
+			t.Errorf("SwitchInput(%d) sent % X, want % X", tt.input, got, want)
+		}
+	}
+}
+
+func TestSetLedTimeoutFrame(t *testing.T) {
+	sw, peer := pipeSwitch(t, nil)
+	writes := readWrites(peer)
+	if err := sw.SetLedTimeout(15); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte{0xAA, 0xBB, 0x03, 0x03, 0x0F, 0xEE}
+	if got := next(t, writes); !bytes.Equal(got, want) {
+		t.Errorf("SetLedTimeout(15) sent % X, want % X", got, want)
+	}
+}
+
+func TestMaxLedTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		max  int
+	}{
+		{"default", nil, 30},
+		{"Model8", []Option{WithModel(Model8)}, 30},
+		{"overridden", []Option{WithMaxLedTimeout(60)}, 60},
+		{"lowered", []Option{WithModel(Model16), WithMaxLedTimeout(10)}, 10},
+		{"out of range is ignored", []Option{WithMaxLedTimeout(256)}, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sw := dryRunSwitch(t, tt.opts...)
+			if got := sw.MaxLedTimeout(); got != tt.max {
+				t.Errorf("MaxLedTimeout() = %d, want %d", got, tt.max)
+			}
+
+			for _, secs := range []int{-1, 0, tt.max, tt.max + 1} {
+				valid := secs >= 0 && secs <= tt.max
+				want := []byte{0xAA, 0xBB, 0x03, 0x03, byte(secs), 0xEE}
+
+				err := sw.SetLedTimeoutContext(context.Background(), secs)
+				if valid != (err == nil) {
+					t.Errorf("SetLedTimeout(%d) = %v, want valid %v", secs, err, valid)
+				}
+				if err != nil && !errors.Is(err, ErrInvalidInput) {
+					t.Errorf("SetLedTimeout(%d) = %v, want ErrInvalidInput", secs, err)
+				}
+				if valid {
+					if sent := sw.SentCommands(); !bytes.Equal(sent[len(sent)-1], want) {
+						t.Errorf("SetLedTimeout(%d) sent % X, want % X", secs, sent[len(sent)-1], want)
+					}
+				}
+
+				err = sw.ExecBatch(context.Background(), Command{Kind: CmdSetLedTimeout, Arg: secs})
+				if valid != (err == nil) {
+					t.Errorf("ExecBatch(CmdSetLedTimeout, %d) = %v, want valid %v", secs, err, valid)
+				}
+				if valid {
+					if sent := sw.SentCommands(); !bytes.Equal(sent[len(sent)-1], want) {
+						t.Errorf("ExecBatch(CmdSetLedTimeout, %d) sent % X, want % X", secs, sent[len(sent)-1], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestConcurrentSends(t *testing.T) {
+	const goroutines, commands = 8, 50
+
+	client, peer := net.Pipe()
+	defer peer.Close()
+	// One byte per Write gives other goroutines every chance to interleave.
+	sw, err := NewTesmartSwitchWithConn(chunkConn{client, 1}, nil, WithHealthCheck(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		data, _ := io.ReadAll(io.LimitReader(peer, goroutines*commands*6))
+		received <- data
+	}()
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < commands; i++ {
+				var err error
+				if (g+i)%2 == 0 {
+					err = sw.SwitchInput(g%8 + 1)
+				} else {
+					err = sw.MuteBuzzer()
+				}
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	data := next(t, received)
+	if len(data) != goroutines*commands*6 {
+		t.Fatalf("received %d bytes, want %d", len(data), goroutines*commands*6)
+	}
+	mutes := 0
+	for i := 0; i < len(data); i += 6 {
+		frame := data[i : i+6]
+		switch {
+		case bytes.Equal(frame, MUTE_BUZZER):
+			mutes++
+		case bytes.Equal(frame[:4], SWITCH_INPUT[:4]) && frame[4] >= 1 && frame[4] <= 8 && frame[5] == SWITCH_INPUT[5]:
+		default:
+			t.Fatalf("frame %d is corrupt: % X", i/6, frame)
+		}
+	}
+	if want := goroutines * commands / 2; mutes != want {
+		t.Errorf("received %d mute frames, want %d", mutes, want)
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	tests := []struct {
+		host, port string
+		valid      bool
+	}{
+		{"192.0.2.1", "5000", true},
+		{"kvm.example.com", "5000", true},
+		{"::1", "5000", true},
+		{"fe80::1%eth0", "5000", true},
+		{"2001:db8::10", "65535", true},
+		{"", "5000", false},
+		{"192.0.2.1:5000", "5000", false},
+		{"[::1]:5000", "5000", false},
+		{"192.0.2.1", "", false},
+		{"192.0.2.1", "http", false},
+		{"192.0.2.1", "0", false},
+		{"192.0.2.1", "65536", false},
+	}
+	for _, tt := range tests {
+		if err := validateAddress(tt.host, tt.port); (err == nil) != tt.valid {
+			t.Errorf("validateAddress(%q, %q) = %v, want valid %v", tt.host, tt.port, err, tt.valid)
+		}
+	}
+}
+
+func TestSkipRedundantSwitch(t *testing.T) {
+	frame := func(input int) []byte {
+		b, _ := BuildSwitchInput(input)
+		return b
+	}
+
+	tests := []struct {
+		name     string
+		skip     bool
+		reported int // 0 for no report
+		input    int
+		sent     bool
+	}{
+		{"unknown", true, 0, 3, true},
+		{"cached equal", true, 3, 3, false},
+		{"cached different", true, 3, 4, true},
+		{"option off", false, 3, 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sw, peer := pipeSwitch(t, nil, WithSkipRedundantSwitch(tt.skip))
+			writes := readWrites(peer)
+			if tt.reported != 0 {
+				// The second write only completes once the report is
+				// handled and the receive loop reads again.
+				peer.Write(report(tt.reported))
+				peer.Write([]byte{0x00})
+			}
+
+			if err := sw.SwitchInput(tt.input); err != nil {
+				t.Fatal(err)
+			}
+			// A switch to another input shows whether the first one was
+			// written.
+			if err := sw.SwitchInput(tt.input%8 + 1); err != nil {
+				t.Fatal(err)
+			}
+
+			want := frame(tt.input%8 + 1)
+			if tt.sent {
+				want = frame(tt.input)
+			}
+			if got := next(t, writes); !bytes.Equal(got, want) {
+				t.Errorf("first write % X, want % X", got, want)
+			}
+		})
+	}
+}
+
+func TestDisableLedTimeout(t *testing.T) {
+	sw := dryRunSwitch(t)
+	if err := sw.DisableLedTimeout(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := sw.SetLedTimeout(0); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := sw.SentCommands()
+	if len(sent) != 2 || !bytes.Equal(sent[0], sent[1]) {
+		t.Errorf("DisableLedTimeout and SetLedTimeout(0) sent % X, want the same frame", sent)
+	}
+}
+
+func TestExtractInput(t *testing.T) {
+	if input, err := ExtractInput(report(5)); err != nil || input != 5 {
+		t.Errorf("ExtractInput(% X) = %d, %v, want 5", report(5), input, err)
+	}
+
+	for _, frame := range [][]byte{nil, {}, {0xAA, 0xBB, 0x03}, report(5)[:5]} {
+		if _, err := ExtractInput(frame); !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("ExtractInput(% X) = %v, want ErrInvalidResponse", frame, err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package tesmartgrpc exposes a TESmart switch as the gRPC service
+// tesmart.v1.Switch defined in tesmartpb/tesmart.proto. It lives in its own
+// package so that only programs importing it depend on gRPC.
+//
+// The server learns about input changes through the switch's response
+// receiver, so it has to be registered when the switch is created:
+//
+//	srv := tesmartgrpc.New()
+//	sw, err := commands.NewTesmartSwitch(host, port, nil,
+//		commands.WithResponseReceiver(srv.HandleResponse))
+//	...
+//	srv.Attach(sw)
+//	g := grpc.NewServer()
+//	tesmartpb.RegisterSwitchServer(g, srv)
+package tesmartgrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	commands "github.com/mfds/tesmart-commands"
+	"github.com/mfds/tesmart-commands/tesmartgrpc/tesmartpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// watchBuffer is how many input changes are queued for a WatchInput stream
+// before older ones are dropped.
+const watchBuffer = 16
+
+// Server implements tesmartpb.SwitchServer for a single switch.
+type Server struct {
+	tesmartpb.UnimplementedSwitchServer
+
+	mu       sync.Mutex
+	sw       commands.Controller
+	input    int // 0 until the first report
+	watchers map[chan int]struct{}
+}
+
+// New returns a Server without a switch; RPCs fail with Unavailable until
+// Attach is called.
+func New() *Server {
+	return &Server{watchers: make(map[chan int]struct{})}
+}
+
+// Attach sets the switch the server controls. The caller keeps ownership of
+// sw.
+func (s *Server) Attach(sw commands.Controller) {
+	s.mu.Lock()
+	s.sw = sw
+	s.mu.Unlock()
+}
+
+// HandleResponse forwards the input reported by the switch to the
+// WatchInput streams if it changed.
+func (s *Server) HandleResponse(r commands.Response) {
+	if r.Type != commands.ResponseInput {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Input == s.input {
+		return
+	}
+	s.input = r.Input
+
+	for ch := range s.watchers {
+		offer(ch, r.Input)
+	}
+}
+
+// offer queues input on ch, dropping the oldest queued change if ch is full.
+func offer(ch chan int, input int) {
+	for {
+		select {
+		case ch <- input:
+			return
+		default:
+		}
+
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
+
+func (s *Server) SwitchInput(ctx context.Context, req *tesmartpb.SwitchInputRequest) (*tesmartpb.SwitchInputResponse, error) {
+	sw, err := s.attached()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sw.SwitchInputContext(ctx, int(req.GetInput())); err != nil {
+		return nil, toStatus(err)
+	}
+	return &tesmartpb.SwitchInputResponse{}, nil
+}
+
+func (s *Server) GetCurrentInput(ctx context.Context, req *tesmartpb.GetCurrentInputRequest) (*tesmartpb.GetCurrentInputResponse, error) {
+	sw, err := s.attached()
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := sw.GetCurrentInput(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &tesmartpb.GetCurrentInputResponse{Input: int32(input)}, nil
+}
+
+func (s *Server) SetBuzzer(ctx context.Context, req *tesmartpb.SetBuzzerRequest) (*tesmartpb.SetBuzzerResponse, error) {
+	sw, err := s.attached()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sw.SetBuzzerContext(ctx, req.GetEnabled()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &tesmartpb.SetBuzzerResponse{}, nil
+}
+
+func (s *Server) SetLedTimeout(ctx context.Context, req *tesmartpb.SetLedTimeoutRequest) (*tesmartpb.SetLedTimeoutResponse, error) {
+	sw, err := s.attached()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sw.SetLedTimeoutContext(ctx, int(req.GetSeconds())); err != nil {
+		return nil, toStatus(err)
+	}
+	return &tesmartpb.SetLedTimeoutResponse{}, nil
+}
+
+func (s *Server) WatchInput(req *tesmartpb.WatchInputRequest, stream tesmartpb.Switch_WatchInputServer) error {
+	ch := make(chan int, watchBuffer)
+
+	s.mu.Lock()
+	if s.input != 0 {
+		ch <- s.input
+	}
+	s.watchers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.watchers, ch)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case input := <-ch:
+			if err := stream.Send(&tesmartpb.InputEvent{Input: int32(input)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) attached() (commands.Controller, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sw == nil {
+		return nil, status.Error(codes.Unavailable, "no switch attached")
+	}
+	return s.sw, nil
+}
+
+// toStatus maps errors returned by the switch to gRPC status codes.
+func toStatus(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, commands.ErrInvalidInput):
+		code = codes.InvalidArgument
+	case errors.Is(err, commands.ErrNotConnected):
+		code = codes.Unavailable
+	case errors.Is(err, commands.ErrUnsupported):
+		code = codes.Unimplemented
+	case errors.Is(err, commands.ErrRateLimited):
+		code = codes.ResourceExhausted
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	}
+	return status.Error(code, err.Error())
+}
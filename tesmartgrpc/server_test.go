@@ -0,0 +1,33 @@
+package tesmartgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	commands "github.com/mfds/tesmart-commands"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{commands.ErrInvalidInput, codes.InvalidArgument},
+		{commands.ErrNotConnected, codes.Unavailable},
+		{commands.ErrUnsupported, codes.Unimplemented},
+		{commands.ErrRateLimited, codes.ResourceExhausted},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{errors.New("boom"), codes.Internal},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("switch input 3: %w", tt.err)
+		if got := status.Code(toStatus(err)); got != tt.want {
+			t.Errorf("toStatus(%v) = %v, want %v", err, got, tt.want)
+		}
+	}
+}
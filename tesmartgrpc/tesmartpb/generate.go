@@ -0,0 +1,5 @@
+// Package tesmartpb holds the protobuf messages and gRPC stubs of the
+// tesmart.v1.Switch service, generated from tesmart.proto.
+package tesmartpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative tesmart.proto
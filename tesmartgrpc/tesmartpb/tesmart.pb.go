@@ -0,0 +1,555 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.35.2
+// 	protoc        (unknown)
+// source: tesmart.proto
+
+package tesmartpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SwitchInputRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 1-based input number.
+	Input int32 `protobuf:"varint,1,opt,name=input,proto3" json:"input,omitempty"`
+}
+
+func (x *SwitchInputRequest) Reset() {
+	*x = SwitchInputRequest{}
+	mi := &file_tesmart_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SwitchInputRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwitchInputRequest) ProtoMessage() {}
+
+func (x *SwitchInputRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tesmart_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwitchInputRequest.ProtoReflect.Descriptor instead.
+func (*SwitchInputRequest) Descriptor() ([]byte, []int) {
+	return file_tesmart_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SwitchInputRequest) GetInput() int32 {
+	if x != nil {
+		return x.Input
+	}
+	return 0
+}
+
+type SwitchInputResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SwitchInputResponse) Reset() {
+	*x = SwitchInputResponse{}
+	mi := &file_tesmart_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SwitchInputResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SwitchInputResponse) ProtoMessage() {}
+
+func (x *SwitchInputResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tesmart_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SwitchInputResponse.ProtoReflect.Descriptor instead.
+func (*SwitchInputResponse) Descriptor() ([]byte, []int) {
+	return file_tesmart_proto_rawDescGZIP(), []int{1}
+}
+
+type GetCurrentInputRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetCurrentInputRequest) Reset() {
+	*x = GetCurrentInputRequest{}
+	mi := &file_tesmart_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentInputRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentInputRequest) ProtoMessage() {}
+
+func (x *GetCurrentInputRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tesmart_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentInputRequest.ProtoReflect.Descriptor instead.
+func (*GetCurrentInputRequest) Descriptor() ([]byte, []int) {
+	return file_tesmart_proto_rawDescGZIP(), []int{2}
+}
+
+type GetCurrentInputResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 1-based input number.
+	Input int32 `protobuf:"varint,1,opt,name=input,proto3" json:"input,omitempty"`
+}
+
+func (x *GetCurrentInputResponse) Reset() {
+	*x = GetCurrentInputResponse{}
+	mi := &file_tesmart_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCurrentInputResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCurrentInputResponse) ProtoMessage() {}
+
+func (x *GetCurrentInputResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tesmart_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCurrentInputResponse.ProtoReflect.Descriptor instead.
+func (*GetCurrentInputResponse) Descriptor() ([]byte, []int) {
+	return file_tesmart_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetCurrentInputResponse) GetInput() int32 {
+	if x != nil {
+		return x.Input
+	}
+	return 0
+}
+
+type SetBuzzerRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Enabled bool `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+}
+
+func (x *SetBuzzerRequest) Reset() {
+	*x = SetBuzzerRequest{}
+	mi := &file_tesmart_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetBuzzerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBuzzerRequest) ProtoMessage() {}
+
+func (x *SetBuzzerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tesmart_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBuzzerRequest.ProtoReflect.Descriptor instead.
+func (*SetBuzzerRequest) Descriptor() ([]byte, []int) {
+	return file_tesmart_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SetBuzzerRequest) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+type SetBuzzerResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SetBuzzerResponse) Reset() {
+	*x = SetBuzzerResponse{}
+	mi := &file_tesmart_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetBuzzerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetBuzzerResponse) ProtoMessage() {}
+
+func (x *SetBuzzerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tesmart_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetBuzzerResponse.ProtoReflect.Descriptor instead.
+func (*SetBuzzerResponse) Descriptor() ([]byte, []int) {
+	return file_tesmart_proto_rawDescGZIP(), []int{5}
+}
+
+type SetLedTimeoutRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Seconds int32 `protobuf:"varint,1,opt,name=seconds,proto3" json:"seconds,omitempty"`
+}
+
+func (x *SetLedTimeoutRequest) Reset() {
+	*x = SetLedTimeoutRequest{}
+	mi := &file_tesmart_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLedTimeoutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLedTimeoutRequest) ProtoMessage() {}
+
+func (x *SetLedTimeoutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tesmart_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLedTimeoutRequest.ProtoReflect.Descriptor instead.
+func (*SetLedTimeoutRequest) Descriptor() ([]byte, []int) {
+	return file_tesmart_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SetLedTimeoutRequest) GetSeconds() int32 {
+	if x != nil {
+		return x.Seconds
+	}
+	return 0
+}
+
+type SetLedTimeoutResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SetLedTimeoutResponse) Reset() {
+	*x = SetLedTimeoutResponse{}
+	mi := &file_tesmart_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLedTimeoutResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLedTimeoutResponse) ProtoMessage() {}
+
+func (x *SetLedTimeoutResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tesmart_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLedTimeoutResponse.ProtoReflect.Descriptor instead.
+func (*SetLedTimeoutResponse) Descriptor() ([]byte, []int) {
+	return file_tesmart_proto_rawDescGZIP(), []int{7}
+}
+
+type WatchInputRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *WatchInputRequest) Reset() {
+	*x = WatchInputRequest{}
+	mi := &file_tesmart_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchInputRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchInputRequest) ProtoMessage() {}
+
+func (x *WatchInputRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tesmart_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchInputRequest.ProtoReflect.Descriptor instead.
+func (*WatchInputRequest) Descriptor() ([]byte, []int) {
+	return file_tesmart_proto_rawDescGZIP(), []int{8}
+}
+
+type InputEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 1-based input number.
+	Input int32 `protobuf:"varint,1,opt,name=input,proto3" json:"input,omitempty"`
+}
+
+func (x *InputEvent) Reset() {
+	*x = InputEvent{}
+	mi := &file_tesmart_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InputEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InputEvent) ProtoMessage() {}
+
+func (x *InputEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_tesmart_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InputEvent.ProtoReflect.Descriptor instead.
+func (*InputEvent) Descriptor() ([]byte, []int) {
+	return file_tesmart_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *InputEvent) GetInput() int32 {
+	if x != nil {
+		return x.Input
+	}
+	return 0
+}
+
+var File_tesmart_proto protoreflect.FileDescriptor
+
+var file_tesmart_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x0a, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2e, 0x76, 0x31, 0x22, 0x2a, 0x0a, 0x12, 0x53,
+	0x77, 0x69, 0x74, 0x63, 0x68, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x22, 0x15, 0x0a, 0x13, 0x53, 0x77, 0x69, 0x74, 0x63,
+	0x68, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x18,
+	0x0a, 0x16, 0x47, 0x65, 0x74, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x49, 0x6e, 0x70, 0x75,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x2f, 0x0a, 0x17, 0x47, 0x65, 0x74, 0x43,
+	0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x22, 0x2c, 0x0a, 0x10, 0x53, 0x65, 0x74,
+	0x42, 0x75, 0x7a, 0x7a, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a,
+	0x07, 0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07,
+	0x65, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x64, 0x22, 0x13, 0x0a, 0x11, 0x53, 0x65, 0x74, 0x42, 0x75,
+	0x7a, 0x7a, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x30, 0x0a, 0x14,
+	0x53, 0x65, 0x74, 0x4c, 0x65, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x22, 0x17,
+	0x0a, 0x15, 0x53, 0x65, 0x74, 0x4c, 0x65, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x13, 0x0a, 0x11, 0x57, 0x61, 0x74, 0x63, 0x68,
+	0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x22, 0x0a, 0x0a,
+	0x49, 0x6e, 0x70, 0x75, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e,
+	0x70, 0x75, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74,
+	0x32, 0x9b, 0x03, 0x0a, 0x06, 0x53, 0x77, 0x69, 0x74, 0x63, 0x68, 0x12, 0x4e, 0x0a, 0x0b, 0x53,
+	0x77, 0x69, 0x74, 0x63, 0x68, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x1e, 0x2e, 0x74, 0x65, 0x73,
+	0x6d, 0x61, 0x72, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x77, 0x69, 0x74, 0x63, 0x68, 0x49, 0x6e,
+	0x70, 0x75, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x74, 0x65, 0x73,
+	0x6d, 0x61, 0x72, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x77, 0x69, 0x74, 0x63, 0x68, 0x49, 0x6e,
+	0x70, 0x75, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x5a, 0x0a, 0x0f, 0x47,
+	0x65, 0x74, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x22,
+	0x2e, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x43,
+	0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x23, 0x2e, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2e, 0x76, 0x31, 0x2e,
+	0x47, 0x65, 0x74, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x48, 0x0a, 0x09, 0x53, 0x65, 0x74, 0x42, 0x75,
+	0x7a, 0x7a, 0x65, 0x72, 0x12, 0x1c, 0x2e, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x65, 0x74, 0x42, 0x75, 0x7a, 0x7a, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2e, 0x76, 0x31, 0x2e,
+	0x53, 0x65, 0x74, 0x42, 0x75, 0x7a, 0x7a, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x54, 0x0a, 0x0d, 0x53, 0x65, 0x74, 0x4c, 0x65, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x12, 0x20, 0x2e, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2e, 0x76, 0x31, 0x2e,
+	0x53, 0x65, 0x74, 0x4c, 0x65, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x65, 0x74, 0x4c, 0x65, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x45, 0x0a, 0x0a, 0x57, 0x61, 0x74, 0x63, 0x68,
+	0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x1d, 0x2e, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2e,
+	0x76, 0x31, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2e, 0x76,
+	0x31, 0x2e, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x42, 0x38,
+	0x5a, 0x36, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6d, 0x66, 0x64,
+	0x73, 0x2f, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x2d, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
+	0x64, 0x73, 0x2f, 0x74, 0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x67, 0x72, 0x70, 0x63, 0x2f, 0x74,
+	0x65, 0x73, 0x6d, 0x61, 0x72, 0x74, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_tesmart_proto_rawDescOnce sync.Once
+	file_tesmart_proto_rawDescData = file_tesmart_proto_rawDesc
+)
+
+func file_tesmart_proto_rawDescGZIP() []byte {
+	file_tesmart_proto_rawDescOnce.Do(func() {
+		file_tesmart_proto_rawDescData = protoimpl.X.CompressGZIP(file_tesmart_proto_rawDescData)
+	})
+	return file_tesmart_proto_rawDescData
+}
+
+var file_tesmart_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_tesmart_proto_goTypes = []any{
+	(*SwitchInputRequest)(nil),      // 0: tesmart.v1.SwitchInputRequest
+	(*SwitchInputResponse)(nil),     // 1: tesmart.v1.SwitchInputResponse
+	(*GetCurrentInputRequest)(nil),  // 2: tesmart.v1.GetCurrentInputRequest
+	(*GetCurrentInputResponse)(nil), // 3: tesmart.v1.GetCurrentInputResponse
+	(*SetBuzzerRequest)(nil),        // 4: tesmart.v1.SetBuzzerRequest
+	(*SetBuzzerResponse)(nil),       // 5: tesmart.v1.SetBuzzerResponse
+	(*SetLedTimeoutRequest)(nil),    // 6: tesmart.v1.SetLedTimeoutRequest
+	(*SetLedTimeoutResponse)(nil),   // 7: tesmart.v1.SetLedTimeoutResponse
+	(*WatchInputRequest)(nil),       // 8: tesmart.v1.WatchInputRequest
+	(*InputEvent)(nil),              // 9: tesmart.v1.InputEvent
+}
+var file_tesmart_proto_depIdxs = []int32{
+	0, // 0: tesmart.v1.Switch.SwitchInput:input_type -> tesmart.v1.SwitchInputRequest
+	2, // 1: tesmart.v1.Switch.GetCurrentInput:input_type -> tesmart.v1.GetCurrentInputRequest
+	4, // 2: tesmart.v1.Switch.SetBuzzer:input_type -> tesmart.v1.SetBuzzerRequest
+	6, // 3: tesmart.v1.Switch.SetLedTimeout:input_type -> tesmart.v1.SetLedTimeoutRequest
+	8, // 4: tesmart.v1.Switch.WatchInput:input_type -> tesmart.v1.WatchInputRequest
+	1, // 5: tesmart.v1.Switch.SwitchInput:output_type -> tesmart.v1.SwitchInputResponse
+	3, // 6: tesmart.v1.Switch.GetCurrentInput:output_type -> tesmart.v1.GetCurrentInputResponse
+	5, // 7: tesmart.v1.Switch.SetBuzzer:output_type -> tesmart.v1.SetBuzzerResponse
+	7, // 8: tesmart.v1.Switch.SetLedTimeout:output_type -> tesmart.v1.SetLedTimeoutResponse
+	9, // 9: tesmart.v1.Switch.WatchInput:output_type -> tesmart.v1.InputEvent
+	5, // [5:10] is the sub-list for method output_type
+	0, // [0:5] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_tesmart_proto_init() }
+func file_tesmart_proto_init() {
+	if File_tesmart_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_tesmart_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_tesmart_proto_goTypes,
+		DependencyIndexes: file_tesmart_proto_depIdxs,
+		MessageInfos:      file_tesmart_proto_msgTypes,
+	}.Build()
+	File_tesmart_proto = out.File
+	file_tesmart_proto_rawDesc = nil
+	file_tesmart_proto_goTypes = nil
+	file_tesmart_proto_depIdxs = nil
+}
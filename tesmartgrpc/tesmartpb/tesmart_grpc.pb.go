@@ -0,0 +1,293 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: tesmart.proto
+
+package tesmartpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Switch_SwitchInput_FullMethodName     = "/tesmart.v1.Switch/SwitchInput"
+	Switch_GetCurrentInput_FullMethodName = "/tesmart.v1.Switch/GetCurrentInput"
+	Switch_SetBuzzer_FullMethodName       = "/tesmart.v1.Switch/SetBuzzer"
+	Switch_SetLedTimeout_FullMethodName   = "/tesmart.v1.Switch/SetLedTimeout"
+	Switch_WatchInput_FullMethodName      = "/tesmart.v1.Switch/WatchInput"
+)
+
+// SwitchClient is the client API for Switch service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Switch controls a single TESmart KVM switch.
+type SwitchClient interface {
+	// SwitchInput makes input active.
+	SwitchInput(ctx context.Context, in *SwitchInputRequest, opts ...grpc.CallOption) (*SwitchInputResponse, error)
+	// GetCurrentInput asks the switch for its active input.
+	GetCurrentInput(ctx context.Context, in *GetCurrentInputRequest, opts ...grpc.CallOption) (*GetCurrentInputResponse, error)
+	// SetBuzzer enables or mutes the buzzer.
+	SetBuzzer(ctx context.Context, in *SetBuzzerRequest, opts ...grpc.CallOption) (*SetBuzzerResponse, error)
+	// SetLedTimeout sets the LED timeout in seconds, 0 disables it.
+	SetLedTimeout(ctx context.Context, in *SetLedTimeoutRequest, opts ...grpc.CallOption) (*SetLedTimeoutResponse, error)
+	// WatchInput streams the active input, first the last known one and then
+	// every change, until the client cancels.
+	WatchInput(ctx context.Context, in *WatchInputRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[InputEvent], error)
+}
+
+type switchClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSwitchClient(cc grpc.ClientConnInterface) SwitchClient {
+	return &switchClient{cc}
+}
+
+func (c *switchClient) SwitchInput(ctx context.Context, in *SwitchInputRequest, opts ...grpc.CallOption) (*SwitchInputResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SwitchInputResponse)
+	err := c.cc.Invoke(ctx, Switch_SwitchInput_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchClient) GetCurrentInput(ctx context.Context, in *GetCurrentInputRequest, opts ...grpc.CallOption) (*GetCurrentInputResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCurrentInputResponse)
+	err := c.cc.Invoke(ctx, Switch_GetCurrentInput_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchClient) SetBuzzer(ctx context.Context, in *SetBuzzerRequest, opts ...grpc.CallOption) (*SetBuzzerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetBuzzerResponse)
+	err := c.cc.Invoke(ctx, Switch_SetBuzzer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchClient) SetLedTimeout(ctx context.Context, in *SetLedTimeoutRequest, opts ...grpc.CallOption) (*SetLedTimeoutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetLedTimeoutResponse)
+	err := c.cc.Invoke(ctx, Switch_SetLedTimeout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *switchClient) WatchInput(ctx context.Context, in *WatchInputRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[InputEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Switch_ServiceDesc.Streams[0], Switch_WatchInput_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchInputRequest, InputEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Switch_WatchInputClient = grpc.ServerStreamingClient[InputEvent]
+
+// SwitchServer is the server API for Switch service.
+// All implementations must embed UnimplementedSwitchServer
+// for forward compatibility.
+//
+// Switch controls a single TESmart KVM switch.
+type SwitchServer interface {
+	// SwitchInput makes input active.
+	SwitchInput(context.Context, *SwitchInputRequest) (*SwitchInputResponse, error)
+	// GetCurrentInput asks the switch for its active input.
+	GetCurrentInput(context.Context, *GetCurrentInputRequest) (*GetCurrentInputResponse, error)
+	// SetBuzzer enables or mutes the buzzer.
+	SetBuzzer(context.Context, *SetBuzzerRequest) (*SetBuzzerResponse, error)
+	// SetLedTimeout sets the LED timeout in seconds, 0 disables it.
+	SetLedTimeout(context.Context, *SetLedTimeoutRequest) (*SetLedTimeoutResponse, error)
+	// WatchInput streams the active input, first the last known one and then
+	// every change, until the client cancels.
+	WatchInput(*WatchInputRequest, grpc.ServerStreamingServer[InputEvent]) error
+	mustEmbedUnimplementedSwitchServer()
+}
+
+// UnimplementedSwitchServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSwitchServer struct{}
+
+func (UnimplementedSwitchServer) SwitchInput(context.Context, *SwitchInputRequest) (*SwitchInputResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SwitchInput not implemented")
+}
+func (UnimplementedSwitchServer) GetCurrentInput(context.Context, *GetCurrentInputRequest) (*GetCurrentInputResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCurrentInput not implemented")
+}
+func (UnimplementedSwitchServer) SetBuzzer(context.Context, *SetBuzzerRequest) (*SetBuzzerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBuzzer not implemented")
+}
+func (UnimplementedSwitchServer) SetLedTimeout(context.Context, *SetLedTimeoutRequest) (*SetLedTimeoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLedTimeout not implemented")
+}
+func (UnimplementedSwitchServer) WatchInput(*WatchInputRequest, grpc.ServerStreamingServer[InputEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchInput not implemented")
+}
+func (UnimplementedSwitchServer) mustEmbedUnimplementedSwitchServer() {}
+func (UnimplementedSwitchServer) testEmbeddedByValue()                {}
+
+// UnsafeSwitchServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SwitchServer will
+// result in compilation errors.
+type UnsafeSwitchServer interface {
+	mustEmbedUnimplementedSwitchServer()
+}
+
+func RegisterSwitchServer(s grpc.ServiceRegistrar, srv SwitchServer) {
+	// If the following call pancis, it indicates UnimplementedSwitchServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Switch_ServiceDesc, srv)
+}
+
+func _Switch_SwitchInput_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SwitchInputRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServer).SwitchInput(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Switch_SwitchInput_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServer).SwitchInput(ctx, req.(*SwitchInputRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Switch_GetCurrentInput_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCurrentInputRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServer).GetCurrentInput(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Switch_GetCurrentInput_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServer).GetCurrentInput(ctx, req.(*GetCurrentInputRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Switch_SetBuzzer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBuzzerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServer).SetBuzzer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Switch_SetBuzzer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServer).SetBuzzer(ctx, req.(*SetBuzzerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Switch_SetLedTimeout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLedTimeoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SwitchServer).SetLedTimeout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Switch_SetLedTimeout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SwitchServer).SetLedTimeout(ctx, req.(*SetLedTimeoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Switch_WatchInput_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchInputRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SwitchServer).WatchInput(m, &grpc.GenericServerStream[WatchInputRequest, InputEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Switch_WatchInputServer = grpc.ServerStreamingServer[InputEvent]
+
+// Switch_ServiceDesc is the grpc.ServiceDesc for Switch service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Switch_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tesmart.v1.Switch",
+	HandlerType: (*SwitchServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SwitchInput",
+			Handler:    _Switch_SwitchInput_Handler,
+		},
+		{
+			MethodName: "GetCurrentInput",
+			Handler:    _Switch_GetCurrentInput_Handler,
+		},
+		{
+			MethodName: "SetBuzzer",
+			Handler:    _Switch_SetBuzzer_Handler,
+		},
+		{
+			MethodName: "SetLedTimeout",
+			Handler:    _Switch_SetLedTimeout_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchInput",
+			Handler:       _Switch_WatchInput_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "tesmart.proto",
+}
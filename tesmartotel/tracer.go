@@ -0,0 +1,69 @@
+// Package tesmartotel traces the commands sent to a TESmart switch with
+// OpenTelemetry. It lives in its own package so that only programs importing
+// it depend on OpenTelemetry:
+//
+//	sw, err := commands.NewTesmartSwitch(host, port, nil,
+//		commands.WithTracer(tesmartotel.NewTracer(nil)))
+//
+// Every command becomes a client span named after it, e.g. "tesmart
+// switch_input", a child of the span in the context passed to the command
+// method, with the attributes tesmart.command, tesmart.input (for switches),
+// server.address and server.port. Failed commands record the error and set
+// the span status to Error.
+package tesmartotel
+
+import (
+	"context"
+	"strconv"
+
+	commands "github.com/mfds/tesmart-commands"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans of this package.
+const instrumentationName = "github.com/mfds/tesmart-commands/tesmartotel"
+
+// Tracer implements commands.Tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ commands.Tracer = (*Tracer)(nil)
+
+// NewTracer returns a Tracer creating spans with tp, or with the global
+// tracer provider if tp is nil.
+func NewTracer(tp trace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: tp.Tracer(instrumentationName)}
+}
+
+// StartCommand starts the span of a command.
+func (t *Tracer) StartCommand(ctx context.Context, info commands.CommandInfo) (context.Context, func(error)) {
+	attrs := []attribute.KeyValue{attribute.String("tesmart.command", info.Command)}
+	if info.Input != 0 {
+		attrs = append(attrs, attribute.Int("tesmart.input", info.Input))
+	}
+	if info.Host != "" {
+		attrs = append(attrs, attribute.String("server.address", info.Host))
+	}
+	if port, err := strconv.Atoi(info.Port); err == nil {
+		attrs = append(attrs, attribute.Int("server.port", port))
+	}
+
+	ctx, span := t.tracer.Start(ctx, "tesmart "+info.Command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
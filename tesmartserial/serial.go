@@ -0,0 +1,44 @@
+// Package tesmartserial controls TESmart switches attached to a serial port,
+// e.g. through a USB-serial adapter. The switches speak the same 6-byte
+// frames over RS232 as over TCP, so the result is an ordinary
+// commands.Switch:
+//
+//	sw, err := tesmartserial.New("/dev/ttyUSB0", 0, nil)
+//
+// It is a package of its own so that programs talking to switches over the
+// network do not depend on the serial library.
+package tesmartserial
+
+import (
+	"fmt"
+
+	commands "github.com/mfds/tesmart-commands"
+	"go.bug.st/serial"
+)
+
+// DefaultBaudRate is the RS232 speed of TESmart switches as shipped: 9600
+// baud, 8 data bits, no parity, one stop bit.
+const DefaultBaudRate = 9600
+
+// New controls a switch attached to a serial port, e.g. "/dev/ttyUSB0" or
+// "COM3". A baud of 0 selects DefaultBaudRate. Like any switch created with
+// commands.NewTesmartSwitchWithTransport, it never reopens the port: when
+// the port fails, e.g. because a USB adapter was unplugged, Done is closed
+// and a new switch has to be created.
+func New(device string, baud int, receiverFunc func([]byte), opts ...commands.Option) (*commands.Switch, error) {
+	if baud == 0 {
+		baud = DefaultBaudRate
+	}
+
+	port, err := serial.Open(device, &serial.Mode{
+		BaudRate: baud,
+		DataBits: 8,
+		Parity:   serial.NoParity,
+		StopBits: serial.OneStopBit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", device, err)
+	}
+
+	return commands.NewTesmartSwitchWithTransport(port, receiverFunc, opts...)
+}
@@ -0,0 +1,19 @@
+package tesmartserial
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewMissingDevice(t *testing.T) {
+	device := filepath.Join(t.TempDir(), "ttyUSB9")
+	sw, err := New(device, 0, nil)
+	if err == nil {
+		sw.Close()
+		t.Fatalf("New(%q) succeeded", device)
+	}
+	if !strings.Contains(err.Error(), device) {
+		t.Errorf("New(%q) = %v, want the device in the error", device, err)
+	}
+}
@@ -0,0 +1,199 @@
+// Package tesmarttest provides a simulated TESmart switch for testing code
+// that talks to one, in the spirit of net/http/httptest:
+//
+//	srv := tesmarttest.NewServer(16)
+//	defer srv.Close()
+//
+//	sw, err := commands.NewTesmartSwitch(srv.Host(), srv.Port(), nil)
+package tesmarttest
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	commands "github.com/mfds/tesmart-commands"
+)
+
+// Server is a simulated switch listening on a loopback TCP port. It keeps
+// track of the active input, answers GET_CURRENT_INPUT and reports the new
+// input to every client after a switch, like the real device. The other
+// commands are accepted and recorded but not answered.
+type Server struct {
+	ln     net.Listener
+	inputs int
+
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	input    int // 1-based
+	received [][]byte
+	closed   bool
+
+	wg sync.WaitGroup
+}
+
+// NewServer starts a simulated switch with the given number of inputs, with
+// input 1 active. It panics if it cannot listen, as tests have no use for a
+// Server that is not running.
+func NewServer(inputs int) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("tesmarttest: failed to listen: " + err.Error())
+	}
+
+	s := &Server{
+		ln:     ln,
+		inputs: inputs,
+		conns:  make(map[net.Conn]struct{}),
+		input:  1,
+	}
+
+	s.wg.Add(1)
+	go s.accept()
+
+	return s
+}
+
+// Addr returns the address the server listens on, as host:port.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Host returns the host part of Addr.
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr())
+	return host
+}
+
+// Port returns the port part of Addr.
+func (s *Server) Port() string {
+	_, port, _ := net.SplitHostPort(s.Addr())
+	return port
+}
+
+// Input returns the active input.
+func (s *Server) Input() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.input
+}
+
+// SetInput makes input active as if it had been selected on the device and
+// reports it to every client.
+func (s *Server) SetInput(input int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.input = input
+	s.broadcastLocked(report(input))
+}
+
+// Received returns a copy of every frame received so far, in order.
+func (s *Server) Received() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frames := make([][]byte, len(s.received))
+	for i, frame := range s.received {
+		frames[i] = append([]byte(nil), frame...)
+	}
+	return frames
+}
+
+// Close stops listening, disconnects every client and waits for the server's
+// goroutines to exit.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		s.ln.Close()
+		for conn := range s.conns {
+			conn.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	frame := make([]byte, 6)
+	for {
+		if _, err := io.ReadFull(conn, frame); err != nil {
+			return
+		}
+		s.handle(conn, frame)
+	}
+}
+
+func (s *Server) handle(conn net.Conn, frame []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.received = append(s.received, append([]byte(nil), frame...))
+
+	switch {
+	case frame[3] == commands.SWITCH_INPUT[3]:
+		input := int(frame[4])
+		if input < 1 || input > s.inputs {
+			return
+		}
+		s.input = input
+		s.broadcastLocked(report(input))
+	case bytes.Equal(frame, commands.BuildGetCurrentInput()):
+		write(conn, report(s.input))
+	}
+}
+
+func (s *Server) broadcastLocked(frame []byte) {
+	for conn := range s.conns {
+		write(conn, frame)
+	}
+}
+
+// writeTimeout keeps a client that stopped reading from blocking the server.
+const writeTimeout = time.Second
+
+func write(conn net.Conn, frame []byte) {
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	conn.Write(frame)
+}
+
+// report builds the frame the switch sends to announce input, see
+// commands.ExtractInput.
+func report(input int) []byte {
+	in := byte(input - 1)
+	return append(append([]byte(nil), commands.OUTPUT...), in, commands.DefaultProtocol.Checksum(in))
+}
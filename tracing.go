@@ -0,0 +1,50 @@
+package commands
+
+import "context"
+
+// Tracer follows each command sent to the switch, e.g. to trace requests
+// through to the device; package tesmartotel implements it for
+// OpenTelemetry. Implementations must be safe for concurrent use.
+type Tracer interface {
+	// StartCommand is called before a command is sent, with the context of
+	// the call that sends it. The returned context is used while sending, and
+	// end is called with the outcome once the command is written or has
+	// failed. Waiting for the rate limit and retries are part of it, waiting
+	// for an answer is not.
+	StartCommand(ctx context.Context, info CommandInfo) (_ context.Context, end func(err error))
+}
+
+// CommandInfo describes a command for a Tracer.
+type CommandInfo struct {
+	Command string // short name such as "switch_input", see Metrics
+	Input   int    // the input switched to by "switch_input", 0 otherwise
+	Host    string // address of the switch, "" if it was not dialed
+	Port    string
+}
+
+// WithTracer reports every command sent to tr. Health check probes are not
+// traced, so an idle switch opens no spans.
+func WithTracer(tr Tracer) Option {
+	return func(t *Switch) {
+		if tr != nil {
+			t.tracer = tr
+		}
+	}
+}
+
+type noTracer struct{}
+
+func (noTracer) StartCommand(ctx context.Context, _ CommandInfo) (context.Context, func(error)) {
+	return ctx, endNothing
+}
+
+func endNothing(error) {}
+
+// commandInfo describes command, in the default layout, for the tracer.
+func (t *Switch) commandInfo(command []byte) CommandInfo {
+	info := CommandInfo{Command: commandName(command), Host: t.host, Port: t.port}
+	if info.Command == "switch_input" {
+		info.Input = int(command[4])
+	}
+	return info
+}
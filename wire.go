@@ -0,0 +1,47 @@
+package commands
+
+import "fmt"
+
+// Direction tells which way bytes passed to a wire tap travelled.
+type Direction int
+
+const (
+	// Sent bytes were written to the switch.
+	Sent Direction = iota
+	// Received bytes were read from the switch.
+	Received
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Sent:
+		return "sent"
+	case Received:
+		return "received"
+	}
+	return fmt.Sprintf("Direction(%d)", int(d))
+}
+
+// WithWireTap registers a function that sees all traffic with the switch:
+// every frame written, or the part of it that was written before a failure,
+// and every read, before it is split into frames. Unlike the logger it is
+// per switch, which makes it suitable for keeping a full trace, e.g. next to
+// a capture made with WithCapture.
+//
+// tap is called on a goroutine of its own, in the order the traffic happened,
+// with a copy of the bytes, so a slow tap never delays I/O. Calls still queued
+// when the switch is closed are made before Close returns.
+func WithWireTap(tap func(dir Direction, data []byte)) Option {
+	return func(t *Switch) {
+		t.wireTap = tap
+	}
+}
+
+// tap passes a copy of data to the wire tap, if any.
+func (t *Switch) tap(dir Direction, data []byte) {
+	if t.wireTap == nil || len(data) == 0 {
+		return
+	}
+	data = append([]byte(nil), data...)
+	t.tapNotifier.post(func() { t.wireTap(dir, data) })
+}